@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/parser"
+	"github.com/chriserin/ft/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [file...]",
+	Short: "Parse .ft files and report their diagnostics",
+	Long: `Parse .ft files and report each problem as file:line:column, without
+syncing or touching the files. With no arguments every .ft file in fts/ is
+checked. Scenarios carrying an @ft tag another scenario already carries are
+reported too. The diagnostics are recorded in fts/ft.db, replacing those from the
+last check or sync. ft exits non-zero if any error is found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return RunCheck(cmd.OutOrStdout(), args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+func RunCheck(w io.Writer, paths []string) error {
+	store, err := db.OpenProjectStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	matches, err := discoverFtFiles()
+	if err != nil {
+		return err
+	}
+	all := len(paths) == 0
+	if all {
+		paths = matches
+	}
+
+	// A copied @ft tag is only found by looking at every file, so the
+	// files not being checked are parsed too
+	contents := make(map[string][]byte)
+	parsed := make(map[string]*parser.ParsedFile)
+	var parsedPaths []string
+	for _, path := range append(slices.Clone(paths), matches...) {
+		if _, ok := parsed[path]; ok {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		doc, parseErrors := parser.Parse(path, content)
+		contents[path] = content
+		parsed[path] = parser.Transform(doc, path, content, parseErrors)
+		parsedPaths = append(parsedPaths, path)
+	}
+	sort.Strings(parsedPaths)
+	dups := findDuplicateTags(store, parsedPaths, parsed, contents)
+
+	checked := make(map[string]bool)
+	for _, path := range paths {
+		if checked[path] {
+			continue
+		}
+		errs := append(slices.Clone(parsed[path].Errors), duplicateErrors(path, contents[path], false, dups[path], false)...)
+		if err := store.ReplaceDiagnostics(path, diagnosticsOf(path, errs)); err != nil {
+			return fmt.Errorf("recording diagnostics for %s: %w", path, err)
+		}
+		checked[path] = true
+	}
+
+	// A full check also forgets files that are gone from fts/
+	if all {
+		if err := store.DeleteDiagnosticsExcept(checked); err != nil {
+			return fmt.Errorf("clearing diagnostics: %w", err)
+		}
+	}
+
+	diags, err := store.Diagnostics()
+	if err != nil {
+		return fmt.Errorf("querying diagnostics: %w", err)
+	}
+
+	errorCount := 0
+	for _, d := range diags {
+		if !checked[d.FilePath] {
+			continue
+		}
+		ui.DiagnosticLine(w, d.FilePath, d.Line, d.Column, d.Severity, d.Message+lineRange(d.Line, d.EndLine))
+		if d.Severity == "error" {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d error(s) found", errorCount)
+	}
+	return nil
+}
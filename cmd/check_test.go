@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/chriserin/ft/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// @ft:265
+func TestCheck_ReportsDiagnostics(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	original := "# language: xx\nFeature: Login\n  Examples: Orphaned\n    | a |\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(original), 0o644))
+	require.NoError(t, os.WriteFile("fts/logout.ft", []byte("Feature: Logout\n  Scenario: User logs out\n    Given a user\n"), 0o644))
+
+	var buf bytes.Buffer
+	err := RunCheck(&buf, nil)
+
+	require.EqualError(t, err, "2 error(s) found")
+	assert.Equal(t, "fts/login.ft:1:1: error Unknown language \"xx\"\nfts/login.ft:3:3: error Examples must belong to a Scenario Outline (lines 3-4)\n", buf.String())
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 2, fx.CountDiagnostics("fts/login.ft"))
+	assert.Equal(t, 0, fx.CountScenarios(), "check does not sync")
+}
+
+// @ft:266
+func TestCheck_CleanFilesPass(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Examples: Orphaned\n    | a |\n"), 0o644))
+	var buf bytes.Buffer
+	require.Error(t, RunCheck(&buf, nil))
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	buf.Reset()
+	require.NoError(t, RunCheck(&buf, []string{"fts/login.ft"}))
+
+	assert.Empty(t, buf.String())
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountDiagnostics("fts/login.ft"))
+}
+
+// @ft:345
+func TestCheck_KeepsDuplicateTagsForSync(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte(`Feature: Signup
+  @ft:1
+  Scenario: User signs up
+    Given a visitor
+`), 0o644))
+	backdate(t, "fts/login.ft", "fts/signup.ft")
+	assert.Contains(t, runSync(t), "err  fts/signup.ft:2:3 — duplicate @ft:1, also at fts/login.ft:2")
+
+	var buf bytes.Buffer
+	require.EqualError(t, RunCheck(&buf, []string{"fts/signup.ft"}), "1 error(s) found")
+	assert.Equal(t, "fts/signup.ft:2:3: error duplicate @ft:1, also at fts/login.ft:2\n", buf.String())
+
+	assert.Contains(t, runSync(t), "err  fts/signup.ft:2:3 — duplicate @ft:1, also at fts/login.ft:2")
+	out := runSyncWith(t, SyncOptions{FixDuplicates: true})
+	assert.Contains(t, out, "+ @ft:2 User signs up (was duplicate @ft:1)")
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ftIgnorePath lists the .ft files and directories under fts/ that ft skips.
+const ftIgnorePath = "fts/.ftignore"
+
+// discoverFtFiles returns every .ft file under fts/, including nested
+// directories, as sorted slash-separated paths relative to the project root,
+// e.g. "fts/billing/invoices.ft". Files and directories matching a pattern in
+// fts/.ftignore are left out.
+func discoverFtFiles() ([]string, error) {
+	ignore, err := loadFtIgnore()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = filepath.WalkDir("fts", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(filepath.ToSlash(p), "fts/")
+		if p == "fts" {
+			return nil
+		}
+		if d.IsDir() {
+			if ignore.matches(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(p) != ".ft" || ignore.matches(rel, false) {
+			return nil
+		}
+		paths = append(paths, filepath.ToSlash(p))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning fts/: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// displayPath is how a tracked file is shown to the user: its path relative
+// to fts/, e.g. "billing/invoices.ft".
+func displayPath(filePath string) string {
+	return strings.TrimPrefix(filePath, "fts/")
+}
+
+// ignorePattern is one line of fts/.ftignore.
+type ignorePattern struct {
+	glob     string // path.Match pattern
+	anchored bool   // glob contains a slash, so it matches the path from fts/
+	dirOnly  bool   // written with a trailing slash
+	negate   bool   // written with a leading !
+}
+
+// ftIgnore is the parsed fts/.ftignore. The last matching pattern wins, so a
+// later !pattern can re-include something an earlier pattern ignored.
+type ftIgnore []ignorePattern
+
+// loadFtIgnore reads fts/.ftignore. A missing file ignores nothing.
+func loadFtIgnore() (ftIgnore, error) {
+	data, err := os.ReadFile(ftIgnorePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ftIgnorePath, err)
+	}
+	return parseFtIgnore(string(data)), nil
+}
+
+// parseFtIgnore parses a gitignore-style pattern list: one glob per line,
+// blank lines and # comments skipped, a trailing / matching only
+// directories, a leading / or inner / anchoring the glob to fts/, and a
+// leading ! re-including a match.
+func parseFtIgnore(content string) ftIgnore {
+	var ignore ftIgnore
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p ignorePattern
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			p.negate = true
+			line = rest
+		}
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			p.dirOnly = true
+			line = rest
+		}
+		p.anchored = strings.Contains(line, "/")
+		p.glob = strings.TrimPrefix(line, "/")
+		if p.glob != "" {
+			ignore = append(ignore, p)
+		}
+	}
+	return ignore
+}
+
+// matches reports whether rel, a slash-separated path relative to fts/, is
+// ignored.
+func (ig ftIgnore) matches(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range ig {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if !p.anchored {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(p.glob, name); ok {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// walkTestFiles calls fn with every _test.go file under the project root,
+// in walk order, skipping .git and fts/. Entries that can't be read are
+// skipped too.
+func walkTestFiles(fn func(path string, info fs.FileInfo)) error {
+	return filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			base := filepath.Base(path)
+			if base == ".git" || base == "fts" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fn(path, info)
+		return nil
+	})
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/parser"
+)
+
+// duplicateTag is a scenario carrying an @ft:<id> tag that another scenario
+// in fts/ already carries, e.g. after a tagged scenario was copy-pasted.
+type duplicateTag struct {
+	id       int64
+	ps       parser.ParsedScenario
+	skipped  bool   // in a broken region, so it's reported but never retagged
+	nth      int    // which of its file's scenarios tagged id this is, in line order
+	original string // path:line of the tag on the scenario that keeps the id
+}
+
+// findDuplicateTags finds the @ft tags carried by more than one scenario
+// across paths, returning the copies by file. The scenario that keeps the id
+// is the one in the file the id is tracked in, preferring one with the
+// tracked name, and otherwise the first in path and line order.
+func findDuplicateTags(store *db.Store, paths []string, parsed map[string]*parser.ParsedFile, contents map[string][]byte) map[string][]duplicateTag {
+	type occurrence struct {
+		path    string
+		ps      parser.ParsedScenario
+		skipped bool
+	}
+	found := make(map[int64][]occurrence)
+	for _, path := range paths {
+		var occs []occurrence
+		for _, ps := range parsed[path].Scenarios {
+			occs = append(occs, occurrence{path: path, ps: ps})
+		}
+		for _, ps := range parsed[path].Skipped {
+			occs = append(occs, occurrence{path: path, ps: ps, skipped: true})
+		}
+		sort.SliceStable(occs, func(i, j int) bool { return occs[i].ps.Line < occs[j].ps.Line })
+		for _, o := range occs {
+			if id, err := strconv.ParseInt(o.ps.FtTag, 10, 64); err == nil {
+				found[id] = append(found[id], o)
+			}
+		}
+	}
+
+	dups := make(map[string][]duplicateTag)
+	for id, occs := range found {
+		if len(occs) < 2 {
+			continue
+		}
+		orig := 0
+		if d, err := store.ScenarioDetail(id); err == nil {
+			inFile := slices.IndexFunc(occs, func(o occurrence) bool { return o.path == d.FilePath })
+			named := slices.IndexFunc(occs, func(o occurrence) bool { return o.path == d.FilePath && o.ps.Name == d.Name })
+			if named >= 0 {
+				orig = named
+			} else if inFile >= 0 {
+				orig = inFile
+			}
+		}
+
+		o := occs[orig]
+		line, _ := tagPosition(contents[o.path], o.ps.Line, id)
+		original := fmt.Sprintf("%s:%d", o.path, line)
+		nth := make(map[string]int)
+		for i, o := range occs {
+			n := nth[o.path]
+			nth[o.path]++
+			if i == orig {
+				continue
+			}
+			dups[o.path] = append(dups[o.path], duplicateTag{id: id, ps: o.ps, skipped: o.skipped, nth: n, original: original})
+		}
+	}
+	for _, d := range dups {
+		sort.Slice(d, func(i, j int) bool { return d[i].ps.Line < d[j].ps.Line })
+	}
+	return dups
+}
+
+// setAsideDuplicates takes the copies out of a file's scenarios, so
+// reconciliation only sees the scenario that keeps each id.
+func setAsideDuplicates(pf *parser.ParsedFile, dups []duplicateTag) {
+	isCopy := func(ps parser.ParsedScenario) bool {
+		return slices.ContainsFunc(dups, func(d duplicateTag) bool { return d.ps.Line == ps.Line })
+	}
+	pf.Scenarios = slices.DeleteFunc(pf.Scenarios, isCopy)
+	pf.Skipped = slices.DeleteFunc(pf.Skipped, isCopy)
+}
+
+// retagDuplicates registers each copy outside a broken region as a new
+// scenario, returning the actions to report and the tags replacing the
+// copied ones. The copies are left in dups for duplicateErrors to skip.
+func retagDuplicates(store *db.Store, fileID int64, dups []duplicateTag) ([]scenarioAction, []tagInsertion, error) {
+	var actions []scenarioAction
+	var insertions []tagInsertion
+	for _, d := range dups {
+		if d.skipped {
+			continue
+		}
+		id, err := store.InsertScenario(fileID, d.ps.Name, d.ps.Rule, d.ps.Content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("inserting scenario %q: %w", d.ps.Name, err)
+		}
+		insertions = append(insertions, tagInsertion{line: d.ps.Line, id: id, replace: d.id})
+		actions = append(actions, scenarioAction{kind: "retagged", id: id, name: d.ps.Name, was: d.id})
+	}
+	return actions, insertions, nil
+}
+
+// duplicateErrors reports the copies still carrying a duplicate tag in a
+// file's content, at the tag itself. Content changed by this sync is
+// re-parsed, so the positions are those of the file as written; retagged
+// copies are left out when fixed is true.
+func duplicateErrors(path string, content []byte, changed bool, dups []duplicateTag, fixed bool) []parser.ParseError {
+	if len(dups) == 0 {
+		return nil
+	}
+	var scenarios []parser.ParsedScenario
+	if changed {
+		doc, errs := parser.Parse(path, content)
+		pf := parser.Transform(doc, path, content, errs)
+		scenarios = append(pf.Scenarios, pf.Skipped...)
+		sort.SliceStable(scenarios, func(i, j int) bool { return scenarios[i].Line < scenarios[j].Line })
+	}
+
+	var errs []parser.ParseError
+	for _, d := range dups {
+		if fixed && !d.skipped {
+			continue
+		}
+		scenarioLine := d.ps.Line
+		if changed {
+			n := 0
+			for _, ps := range scenarios {
+				if ps.FtTag != strconv.FormatInt(d.id, 10) {
+					continue
+				}
+				if n == d.nth {
+					scenarioLine = ps.Line
+					break
+				}
+				n++
+			}
+		}
+		line, column := tagPosition(content, scenarioLine, d.id)
+		errs = append(errs, parser.ParseError{
+			Line:    line,
+			EndLine: line,
+			Column:  column,
+			Message: fmt.Sprintf("duplicate @ft:%d, also at %s", d.id, d.original),
+		})
+	}
+	return errs
+}
+
+// tagPosition returns the 1-based line and column of the @ft:<id> tag among
+// the tag lines directly above the Scenario: on scenarioLine, or the
+// scenario's own line if the tag isn't found.
+func tagPosition(content []byte, scenarioLine int, id int64) (int, int) {
+	cst := parser.ParseCST(content)
+	tag := "@ft:" + strconv.FormatInt(id, 10)
+	for n := scenarioLine - 1; n >= 1 && n <= len(cst.Lines); n-- {
+		l := cst.Lines[n-1]
+		if l.Kind != parser.TagLine && l.Kind != parser.CommentLine {
+			break
+		}
+		if l.Kind != parser.TagLine {
+			continue
+		}
+		off := 0
+		for _, f := range strings.Fields(l.Text) {
+			i := off + strings.Index(l.Text[off:], f)
+			if f == tag {
+				return n, len(l.Indent) + i + 1
+			}
+			off = i + len(f)
+		}
+	}
+	return scenarioLine, 1
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chriserin/ft/internal/parser"
+	"github.com/chriserin/ft/internal/ui"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+var fmtCheck bool
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [file...]",
+	Short: "Rewrite .ft files in the canonical layout",
+	Long: `Rewrite .ft files in the canonical layout: two-space indentation, aligned
+step text and table columns, the @ft:<id> tag first on its tag line, and one
+blank line between scenarios. With no arguments every .ft file in fts/ is
+formatted.
+
+Formatting only changes whitespace and tag order, so it never marks a
+scenario modified.
+
+Use --check in CI: nothing is written, a diff is printed for each file that
+isn't formatted, and ft exits non-zero if there are any.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return RunFmt(cmd.OutOrStdout(), args, fmtCheck)
+	},
+}
+
+func init() {
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "report unformatted files with a diff instead of rewriting them")
+	rootCmd.AddCommand(fmtCmd)
+}
+
+func RunFmt(w io.Writer, paths []string, check bool) error {
+	if len(paths) == 0 {
+		matches, err := discoverFtFiles()
+		if err != nil {
+			return err
+		}
+		paths = matches
+	}
+
+	var unformatted []string
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		formatted := parser.Format(content)
+		if string(formatted) == string(content) {
+			continue
+		}
+		unformatted = append(unformatted, path)
+
+		if check {
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(string(content)),
+				B:        difflib.SplitLines(string(formatted)),
+				FromFile: path,
+				ToFile:   path + " (formatted)",
+				Context:  3,
+			})
+			if err != nil {
+				return fmt.Errorf("diffing %s: %w", path, err)
+			}
+			ui.ShowDiff(w, diff)
+			continue
+		}
+
+		if err := writeFileAtomic(path, formatted); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		ui.FmtLine(w, path)
+	}
+
+	if check && len(unformatted) > 0 {
+		return fmt.Errorf("%d file(s) not formatted", len(unformatted))
+	}
+	return nil
+}
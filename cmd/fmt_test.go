@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/chriserin/ft/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runFmt(t *testing.T, paths ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, RunFmt(&buf, paths, false))
+	return buf.String()
+}
+
+const unformattedLogin = `Feature:  Login
+Scenario: User logs in
+  Given a user
+      When they log in
+  Then they see |x|
+   |name|role|
+   |alice|admin|
+@smoke
+  @ft:1
+Scenario: User logs out
+  Given a logged in user
+
+
+
+`
+
+const formattedLogin = `Feature: Login
+
+  Scenario: User logs in
+    Given a user
+    When  they log in
+    Then  they see |x|
+      | name  | role  |
+      | alice | admin |
+
+  @ft:1 @smoke
+  Scenario: User logs out
+    Given a logged in user
+`
+
+// @ft:253
+func TestFmt_RewritesFileInCanonicalLayout(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(unformattedLogin), 0o644))
+	require.NoError(t, os.WriteFile("fts/done.ft", []byte("Feature: Done\n"), 0o644))
+
+	out := runFmt(t)
+
+	assert.Contains(t, out, "fts/login.ft")
+	assert.NotContains(t, out, "fts/done.ft")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, formattedLogin, string(data))
+}
+
+// @ft:253
+func TestFmt_OnlyNamedFiles(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(unformattedLogin), 0o644))
+	require.NoError(t, os.WriteFile("fts/other.ft", []byte(unformattedLogin), 0o644))
+
+	runFmt(t, "fts/login.ft")
+
+	data, err := os.ReadFile("fts/other.ft")
+	require.NoError(t, err)
+	assert.Equal(t, unformattedLogin, string(data))
+}
+
+// @ft:254
+func TestFmt_CheckReportsDiffWithoutWriting(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(unformattedLogin), 0o644))
+
+	var buf bytes.Buffer
+	err := RunFmt(&buf, nil, true)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 file(s) not formatted")
+	out := buf.String()
+	assert.Contains(t, out, "--- fts/login.ft")
+	assert.Contains(t, out, "-      When they log in")
+	assert.Contains(t, out, "+    When  they log in")
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, unformattedLogin, string(data))
+}
+
+// @ft:255
+func TestFmt_CheckPassesWhenFormatted(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(formattedLogin), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, RunFmt(&buf, nil, true))
+	assert.Empty(t, buf.String())
+}
+
+// @ft:256
+func TestFmt_NeverMarksScenariosModified(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+Scenario: User logs in
+  Given a user
+      When they log in
+   |name|role|
+   |alice|admin|
+  """
+    {"a": 1}
+  """
+`), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "1", "accepted")
+
+	runFmt(t)
+	out := runSync(t)
+
+	assert.NotContains(t, out, "~")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "accepted", fx.Status(1))
+}
@@ -82,7 +82,7 @@ func TestInit_AddsMigrationSystem(t *testing.T) {
 	runInit(t)
 
 	fx := dbtest.Open(t, "fts/ft.db")
-	assert.Equal(t, 6, fx.SchemaVersion())
+	assert.Equal(t, 15, fx.SchemaVersion())
 }
 
 // @ft:6
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/chriserin/ft/internal/lint"
+	"github.com/chriserin/ft/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// lintConfigPath is the optional file that enables or disables lint rules.
+const lintConfigPath = "fts/lint.conf"
+
+var (
+	lintJSON      bool
+	lintListRules bool
+	lintEnable    []string
+	lintDisable   []string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [file...]",
+	Short: "Check .ft files for scenario quality problems",
+	Long: `Check .ft files against the lint rules and report each violation as
+file:line. With no arguments every .ft file in fts/ is checked. ft exits
+non-zero if any issue is found.
+
+Rules are enabled by default. Turn them off or on in fts/lint.conf, one
+"<rule> = on|off" per line, or for a single run with --disable and --enable.
+Use --rules to list them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if lintListRules {
+			return RunLintRules(cmd.OutOrStdout(), lintEnable, lintDisable)
+		}
+		return RunLint(cmd.OutOrStdout(), args, lintEnable, lintDisable, lintJSON)
+	},
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintJSON, "json", false, "print issues as a JSON array")
+	lintCmd.Flags().BoolVar(&lintListRules, "rules", false, "list the lint rules and whether each is enabled")
+	lintCmd.Flags().StringArrayVar(&lintEnable, "enable", nil, "enable this rule (repeatable)")
+	lintCmd.Flags().StringArrayVar(&lintDisable, "disable", nil, "disable this rule (repeatable)")
+	rootCmd.AddCommand(lintCmd)
+}
+
+func RunLint(w io.Writer, paths, enable, disable []string, asJSON bool) error {
+	cfg, err := loadLintConfig(enable, disable)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		matches, err := discoverFtFiles()
+		if err != nil {
+			return err
+		}
+		paths = matches
+	}
+
+	var files []*lint.File
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		files = append(files, lint.NewFile(path, content))
+	}
+
+	issues := lint.Run(files, cfg)
+
+	if asJSON {
+		if issues == nil {
+			issues = []lint.Issue{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, is := range issues {
+			ui.LintIssueLine(w, is.File, is.Line, is.Rule, is.Message)
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("%d lint issue(s) found", len(issues))
+	}
+	return nil
+}
+
+func RunLintRules(w io.Writer, enable, disable []string) error {
+	cfg, err := loadLintConfig(enable, disable)
+	if err != nil {
+		return err
+	}
+	for _, r := range lint.Rules {
+		ui.LintRuleLine(w, r.Name, r.Description, cfg.Enabled(r.Name))
+	}
+	return nil
+}
+
+// loadLintConfig reads fts/lint.conf, if present, then applies the
+// --enable and --disable flags on top of it.
+func loadLintConfig(enable, disable []string) (lint.Config, error) {
+	cfg := lint.Config{}
+	data, err := os.ReadFile(lintConfigPath)
+	switch {
+	case err == nil:
+		cfg, err = lint.ParseConfig(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", lintConfigPath, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("reading %s: %w", lintConfigPath, err)
+	}
+
+	for _, name := range enable {
+		if _, ok := lint.Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		cfg[name] = true
+	}
+	for _, name := range disable {
+		if _, ok := lint.Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		cfg[name] = false
+	}
+	return cfg, nil
+}
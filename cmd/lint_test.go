@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/chriserin/ft/internal/lint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintProblems = `Feature: Login
+  Scenario: User logs in
+    Given a user
+
+  Scenario: User logs in
+    Then ok
+`
+
+// @ft:257
+func TestLint_ReportsIssuesWithFileAndLine(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(lintProblems), 0o644))
+
+	var buf bytes.Buffer
+	err := RunLint(&buf, nil, nil, nil, false)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 lint issue(s) found")
+	out := buf.String()
+	assert.Contains(t, out, "fts/login.ft:2: no-then")
+	assert.Contains(t, out, "fts/login.ft:5: duplicate-scenario-name")
+}
+
+// @ft:257
+func TestLint_CleanFilesPass(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: A\n    Then ok\n"), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, RunLint(&buf, nil, nil, nil, false))
+	assert.Empty(t, buf.String())
+}
+
+// @ft:258
+func TestLint_JSONOutput(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(lintProblems), 0o644))
+
+	var buf bytes.Buffer
+	require.Error(t, RunLint(&buf, nil, nil, nil, true))
+
+	var issues []lint.Issue
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+	assert.Equal(t, []lint.Issue{
+		{File: "fts/login.ft", Line: 2, Rule: "no-then", Message: `scenario "User logs in" has no Then step`},
+		{File: "fts/login.ft", Line: 5, Rule: "duplicate-scenario-name", Message: `scenario "User logs in" is already defined on line 2`},
+	}, issues)
+}
+
+// @ft:258
+func TestLint_JSONOutputWithNoIssuesIsEmptyArray(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, RunLint(&buf, nil, nil, nil, true))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+// @ft:259
+func TestLint_ConfigFileDisablesRules(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(lintProblems), 0o644))
+	require.NoError(t, os.WriteFile("fts/lint.conf", []byte("# no Then needed here\nno-then = off\n"), 0o644))
+
+	var buf bytes.Buffer
+	err := RunLint(&buf, nil, nil, nil, false)
+
+	require.Error(t, err)
+	assert.NotContains(t, buf.String(), "no-then")
+	assert.Contains(t, buf.String(), "duplicate-scenario-name")
+}
+
+// @ft:259
+func TestLint_FlagsOverrideConfigFile(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(lintProblems), 0o644))
+	require.NoError(t, os.WriteFile("fts/lint.conf", []byte("no-then = off\n"), 0o644))
+
+	var buf bytes.Buffer
+	require.Error(t, RunLint(&buf, nil, []string{"no-then"}, []string{"duplicate-scenario-name"}, false))
+
+	assert.Contains(t, buf.String(), "no-then")
+	assert.NotContains(t, buf.String(), "duplicate-scenario-name")
+}
+
+// @ft:259
+func TestLint_UnknownRule(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+
+	var buf bytes.Buffer
+	err := RunLint(&buf, nil, nil, []string{"no-such-rule"}, false)
+	assert.EqualError(t, err, `unknown lint rule "no-such-rule"`)
+}
+
+// @ft:260
+func TestLint_ListRules(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, RunLintRules(&buf, nil, []string{"no-then"}))
+	out := buf.String()
+	for _, r := range lint.Rules {
+		assert.Contains(t, out, r.Name)
+	}
+	assert.Regexp(t, `off\s+no-then`, out)
+	assert.Regexp(t, `on\s+no-steps`, out)
+}
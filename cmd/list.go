@@ -5,13 +5,17 @@ import (
 	"io"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/chriserin/ft/internal/db"
 	"github.com/chriserin/ft/internal/ui"
 	"github.com/spf13/cobra"
 )
 
-var notStatuses []string
+var (
+	notStatuses []string
+	listDir     string
+)
 
 var listCmd = &cobra.Command{
 	Use:   "list [status...]",
@@ -28,20 +32,23 @@ Examples:
   ft list --not removed --not done     Exclude multiple statuses
   ft list tested                       Show only scenarios with linked tests
   ft list --not tested                 Show only scenarios without linked tests
-  ft list ready --not tested           Show ready scenarios missing tests`,
+  ft list ready --not tested           Show ready scenarios missing tests
+  ft list --dir billing                Show scenarios in fts/billing/ and below`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return RunList(cmd.OutOrStdout(), args, notStatuses)
+		return RunList(cmd.OutOrStdout(), args, notStatuses, listDir)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().StringArrayVar(&notStatuses, "not", nil, "exclude scenarios with this status (repeatable)")
+	listCmd.Flags().StringVar(&listDir, "dir", "", "only show scenarios in files under this directory of fts/")
 }
 
 type listRow struct {
 	id       int64
 	fileName string
+	rule     string
 	name     string
 	status   string
 }
@@ -71,7 +78,19 @@ func extractVirtual(filters []string, name string) ([]string, bool) {
 	return remaining, found
 }
 
-func RunList(w io.Writer, includes []string, excludes []string) error {
+// inDir reports whether a tracked file lives in dir or below it. dir is
+// relative to fts/, though a leading "fts/" or "./" is accepted too.
+func inDir(filePath, dir string) bool {
+	dir = strings.TrimPrefix(dir, "./")
+	dir = strings.TrimPrefix(filepath.ToSlash(dir), "fts/")
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" || dir == "fts" || dir == "." {
+		return true
+	}
+	return strings.HasPrefix(displayPath(filePath), dir+"/")
+}
+
+func RunList(w io.Writer, includes []string, excludes []string, dir string) error {
 	store, err := db.OpenProjectStore()
 	if err != nil {
 		return err
@@ -89,9 +108,14 @@ func RunList(w io.Writer, includes []string, excludes []string) error {
 
 	var results []listRow
 	for _, row := range rows {
+		if !inDir(row.FilePath, dir) {
+			continue
+		}
+
 		r := listRow{
 			id:       row.ID,
-			fileName: filepath.Base(row.FilePath),
+			fileName: displayPath(row.FilePath),
+			rule:     row.Rule,
 			name:     row.Name,
 			status:   row.Status,
 		}
@@ -115,7 +139,7 @@ func RunList(w io.Writer, includes []string, excludes []string) error {
 	}
 
 	// Compute column widths
-	idWidth, fileWidth, nameWidth := 0, 0, 0
+	idWidth, fileWidth, ruleWidth, nameWidth := 0, 0, 0, 0
 	for _, r := range results {
 		tag := fmt.Sprintf("@ft:%d", r.id)
 		if len(tag) > idWidth {
@@ -124,13 +148,16 @@ func RunList(w io.Writer, includes []string, excludes []string) error {
 		if len(r.fileName) > fileWidth {
 			fileWidth = len(r.fileName)
 		}
+		if len(r.rule) > ruleWidth {
+			ruleWidth = len(r.rule)
+		}
 		if len(r.name) > nameWidth {
 			nameWidth = len(r.name)
 		}
 	}
 
 	for _, r := range results {
-		ui.ListRow(w, r.id, r.fileName, r.name, r.status, idWidth, fileWidth, nameWidth)
+		ui.ListRow(w, r.id, r.fileName, r.rule, r.name, r.status, idWidth, fileWidth, ruleWidth, nameWidth)
 	}
 
 	return nil
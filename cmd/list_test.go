@@ -13,7 +13,7 @@ import (
 func runList(t *testing.T, includes ...string) string {
 	t.Helper()
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, includes, nil))
+	require.NoError(t, RunList(&buf, includes, nil, ""))
 	return buf.String()
 }
 
@@ -53,6 +53,52 @@ func TestList_MultipleScenariosFromOneFile(t *testing.T) {
 	assert.Contains(t, out, "User fails login")
 }
 
+// @ft:242
+func TestList_IncludesScenarioOutline(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User logs out
+    Given a user
+
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Examples:
+      | role  |
+      | admin |
+`), 0o644))
+	runSync(t)
+
+	out := runList(t)
+
+	assert.Contains(t, out, "User logs out")
+	assert.Contains(t, out, "User logs in as <role>")
+}
+
+// @ft:247
+func TestList_ShowsRuleColumn(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/refunds.ft", []byte(`Feature: Refunds
+  Scenario: Refund page loads
+    Given the refund page
+
+  Rule: Refunds need a receipt
+    Scenario: Refund with receipt
+      Given a receipt
+`), 0o644))
+	runSync(t)
+
+	out := runList(t)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "Refunds need a receipt  Refund with receipt")
+	// The scenario outside a rule leaves the rule column blank but aligned
+	assert.Equal(t, strings.Index(lines[1], "Refund with receipt"), strings.Index(lines[0], "Refund page loads"))
+}
+
 // @ft:40
 func TestList_ScenariosFromMultipleFiles(t *testing.T) {
 	inTempDir(t)
@@ -212,7 +258,7 @@ func TestList_FilterByNegatedStatus(t *testing.T) {
 	runStatusUpdate(t, "3", "removed")
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, nil, []string{"removed"}))
+	require.NoError(t, RunList(&buf, nil, []string{"removed"}, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User logs in")
@@ -265,7 +311,7 @@ func TestList_FilterByMultipleNegatedStatuses(t *testing.T) {
 	runStatusUpdate(t, "2", "removed")
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, nil, []string{"removed", "no-activity"}))
+	require.NoError(t, RunList(&buf, nil, []string{"removed", "no-activity"}, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User logs in")
@@ -292,7 +338,7 @@ func TestList_FilterMixedPositiveAndNegated(t *testing.T) {
 	runStatusUpdate(t, "2", "accepted")
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, []string{"ready"}, []string{"no-activity"}))
+	require.NoError(t, RunList(&buf, []string{"ready"}, []string{"no-activity"}, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User logs in")
@@ -346,7 +392,7 @@ func TestList_RequiresInit(t *testing.T) {
 	inTempDir(t)
 
 	var buf bytes.Buffer
-	err := RunList(&buf, nil, nil)
+	err := RunList(&buf, nil, nil, "")
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "run `ft init` first")
@@ -393,7 +439,7 @@ func TestList_FilterNotTested(t *testing.T) {
 	runSync(t)
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, nil, []string{"tested"}))
+	require.NoError(t, RunList(&buf, nil, []string{"tested"}, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User fails login")
@@ -424,7 +470,7 @@ func TestList_TestedCombinedWithStatusFilter(t *testing.T) {
 	runSync(t)
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, []string{"ready"}, []string{"tested"}))
+	require.NoError(t, RunList(&buf, []string{"ready"}, []string{"tested"}, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User fails login")
@@ -446,3 +492,28 @@ func TestList_TestedNoLinksShowsNothing(t *testing.T) {
 
 	assert.Empty(t, out)
 }
+
+// @ft:272
+func TestList_NestedFilesAndDirFilter(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.MkdirAll("fts/billing", 0o755))
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/billing/invoices.ft", []byte("Feature: Invoices\n  Scenario: Invoice is sent\n    Given an order\n"), 0o644))
+	runSync(t)
+
+	out := runList(t)
+	assert.Contains(t, out, "billing/invoices.ft")
+	assert.Contains(t, out, "login.ft")
+
+	for _, dir := range []string{"billing", "fts/billing/", "./fts/billing"} {
+		var buf bytes.Buffer
+		require.NoError(t, RunList(&buf, nil, nil, dir))
+		assert.Contains(t, buf.String(), "Invoice is sent", dir)
+		assert.NotContains(t, buf.String(), "User logs in", dir)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RunList(&buf, nil, nil, "bill"))
+	assert.Empty(t, buf.String(), "--dir matches whole directory names")
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelMap calls fn on every item using at most GOMAXPROCS goroutines
+// and returns the results in the order of items, so callers that print or
+// write them stay deterministic. fn must not touch the database: the
+// results are meant to be applied by the caller, one after another.
+func parallelMap[T, R any](items []T, fn func(T) R) []R {
+	results := make([]R, len(items))
+	workers := min(runtime.GOMAXPROCS(0), len(items))
+	if workers <= 1 {
+		for i, item := range items {
+			results[i] = fn(item)
+		}
+		return results
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				results[i] = fn(items[i])
+			}
+		})
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// @ft:310
+func TestParallelMap_KeepsItemOrder(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+
+	got := parallelMap(items, func(n int) int { return n * n })
+
+	for i, n := range got {
+		assert.Equal(t, i*i, n)
+	}
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/parser"
+)
+
+// restoredCommentRe matches the comment restoredComment writes, capturing
+// the tags of the scenarios it names.
+var restoredCommentRe = regexp.MustCompile(`^# ft error: scenario\(s\) ((?:@ft:\d+ )+)restored because active test links exist — remove tests before removing scenarios \(line \d+\)$`)
+
+// restoredComment is written to the top of a file that sync writes
+// scenarios back into, so the user sees why they reappeared. It names the
+// scenarios by tag and, like an error comment, gives the line of the first
+// in the file without ft's comments.
+func restoredComment(ids []int64, line int) string {
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&b, "@ft:%d ", id)
+	}
+	return fmt.Sprintf("ft error: scenario(s) %srestored because active test links exist — remove tests before removing scenarios (line %d)", b.String(), line)
+}
+
+// fileHeader returns the part of a .ft file above its first Scenario or
+// Rule and the tags and comments directly above it: the Feature: line,
+// description and Background. Comments written by ft are left out. This is
+// what's stored in files.content to rebuild the file from.
+func fileHeader(content []byte) string {
+	doc, _ := parser.Parse("", content)
+	cst := parser.ParseCST(content)
+	if first := firstBlockLine(doc); first > 0 {
+		cst.Lines = cst.Lines[:blockStart(cst, first)]
+	}
+	stripErrorComments(cst)
+	stripRestoredComment(cst)
+	return strings.TrimRight(strings.ReplaceAll(cst.String(), "\r\n", "\n"), " \t\n")
+}
+
+// firstBlockLine returns the line of the first Scenario or Rule in doc, or
+// 0 if it has neither.
+func firstBlockLine(doc *parser.Document) int {
+	if doc.Feature == nil {
+		return 0
+	}
+	first := 0
+	if len(doc.Feature.Scenarios) > 0 {
+		first = doc.Feature.Scenarios[0].Line
+	}
+	if len(doc.Feature.Rules) > 0 && (first == 0 || doc.Feature.Rules[0].Line < first) {
+		first = doc.Feature.Rules[0].Line
+	}
+	return first
+}
+
+// blockStart returns the 0-based index of the first of the tag and comment
+// lines directly above the 1-based line, or of the line itself.
+func blockStart(cst *parser.CST, line int) int {
+	i := line - 1
+	for i > 0 && (cst.Lines[i-1].Kind == parser.TagLine || cst.Lines[i-1].Kind == parser.CommentLine) {
+		i--
+	}
+	return i
+}
+
+// rebuildFile reconstructs a deleted .ft file from its stored header and
+// the scenarios to write back into it. A file without a stored header gets
+// a Feature: line named after the file.
+func rebuildFile(path string, header string, scenarios []db.ScenarioRecord) []byte {
+	if header == "" {
+		header = parser.LanguageFor(parser.DefaultLanguage).Feature[0] + ": " + strings.TrimSuffix(filepath.Base(path), ".ft")
+	}
+	return appendScenarios([]byte(header+"\n"), scenarios)
+}
+
+// appendScenarios writes stored scenarios back into a file's content, each
+// under its tag line, in ID order. A scenario goes at the end of its
+// Rule:, which is added at the end of the file if it's gone; a scenario
+// outside any rule goes before the first Rule:, or at the end of the file.
+func appendScenarios(content []byte, scenarios []db.ScenarioRecord) []byte {
+	sorted := make([]db.ScenarioRecord, len(scenarios))
+	copy(sorted, scenarios)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	for _, rec := range sorted {
+		doc, _ := parser.Parse("", content)
+		cst := parser.ParseCST(content)
+		lang := parser.LanguageFor(cst.Language)
+		block := scenarioBlock(lang, rec)
+
+		var rules []parser.Rule
+		if doc.Feature != nil {
+			rules = doc.Feature.Rules
+		}
+		at := len(cst.Lines)
+		if rec.Rule == "" {
+			if len(rules) > 0 {
+				at = blockStart(cst, rules[0].Line)
+			}
+		} else {
+			found := false
+			for i, r := range rules {
+				if r.Name == rec.Rule {
+					found = true
+					if i+1 < len(rules) {
+						at = blockStart(cst, rules[i+1].Line)
+					}
+					break
+				}
+			}
+			if !found {
+				block = append([]string{"  " + lang.Rule[0] + ": " + rec.Rule, ""}, block...)
+			}
+		}
+
+		if at > 0 && cst.Lines[at-1].Kind != parser.BlankLine {
+			block = append([]string{""}, block...)
+		}
+		if at < len(cst.Lines) {
+			block = append(block, "")
+		}
+		insertLines(cst, at, block)
+		content = cst.Bytes()
+	}
+	return content
+}
+
+// scenarioBlock returns the lines of a stored scenario under its @ft tag
+// and its other tags, indented to match. A scenario without stored content gets a bare
+// Scenario: line for the user to fill in.
+func scenarioBlock(lang *parser.Language, rec db.ScenarioRecord) []string {
+	content := rec.Content.String
+	if strings.TrimSpace(content) == "" {
+		indent := "  "
+		if rec.Rule != "" {
+			indent = "    "
+		}
+		content = indent + lang.Scenario[0] + ": " + rec.Name
+	}
+	indent := content[:len(content)-len(strings.TrimLeft(content, " \t"))]
+	tags := "@ft:" + strconv.FormatInt(rec.ID, 10)
+	if rec.Tags != "" {
+		tags += " " + rec.Tags
+	}
+	lines := []string{indent + tags}
+	for _, l := range strings.Split(content, "\n") {
+		// Content stored from a CRLF file keeps its \r line endings
+		lines = append(lines, strings.TrimSuffix(l, "\r"))
+	}
+	return lines
+}
+
+// recordScenarioTags stores the tags other than @ft of a file's scenarios,
+// for rehydration to write back. A scenario about to be tagged is found by
+// its insertion, as is a retagged copy; other copies are left alone.
+func recordScenarioTags(store *db.Store, pf *parser.ParsedFile, dups []duplicateTag, insertions []tagInsertion) error {
+	inserted := make(map[int]int64, len(insertions))
+	for _, ins := range insertions {
+		inserted[ins.line] = ins.id
+	}
+	record := func(ps parser.ParsedScenario, tagged bool) error {
+		id, ok := inserted[ps.Line]
+		if !ok {
+			if !tagged {
+				return nil
+			}
+			var err error
+			if id, err = strconv.ParseInt(ps.FtTag, 10, 64); err != nil {
+				return nil
+			}
+		}
+		return store.UpdateScenarioTags(id, strings.Join(ps.OtherTags, " "))
+	}
+	for _, ps := range pf.Scenarios {
+		if err := record(ps, true); err != nil {
+			return err
+		}
+	}
+	for _, d := range dups {
+		if err := record(d.ps, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertLines inserts raw lines before the 0-based line index at, using
+// the file's line ending. A file without a final newline keeps ending
+// without one.
+func insertLines(cst *parser.CST, at int, lines []string) {
+	eol := cst.EOL()
+	added := make([]parser.CSTLine, len(lines))
+	for i, raw := range lines {
+		text := strings.TrimLeft(raw, " \t")
+		added[i] = parser.CSTLine{Indent: raw[:len(raw)-len(text)], Text: text, EOL: eol}
+	}
+	if at == len(cst.Lines) && at > 0 && cst.Lines[at-1].EOL == "" {
+		cst.Lines[at-1].EOL = eol
+		added[len(added)-1].EOL = ""
+	}
+	cst.Lines = append(cst.Lines[:at], append(added, cst.Lines[at:]...)...)
+}
+
+// updateRestoredComment keeps restoredComment at the top of a file's
+// content while tests link to a scenario sync wrote back into it: one of
+// restored, just written back, or one an earlier comment names. Once none
+// of them is linked, or in the file, the comment is removed. Returns the
+// new content and whether it changed.
+func updateRestoredComment(content []byte, restored []int64, linked testLinkIndex) ([]byte, bool, error) {
+	cst := parser.ParseCST(content)
+	named := stripRestoredComment(cst)
+
+	// The line is that of the file without ft's comments
+	bare := parser.ParseCST(cst.Bytes())
+	stripErrorComments(bare)
+	doc, errs := parser.Parse("", bare.Bytes())
+	pf := parser.Transform(doc, "", bare.Bytes(), errs)
+	lines := make(map[int64]int)
+	for _, ps := range append(pf.Scenarios, pf.Skipped...) {
+		if id, err := strconv.ParseInt(ps.FtTag, 10, 64); err == nil {
+			if _, ok := lines[id]; !ok {
+				lines[id] = ps.Line
+			}
+		}
+	}
+
+	var ids []int64
+	line := 0
+	for _, id := range append(named, restored...) {
+		if len(linked[id]) == 0 || lines[id] == 0 || slices.Contains(ids, id) {
+			continue
+		}
+		ids = append(ids, id)
+		if line == 0 || lines[id] < line {
+			line = lines[id]
+		}
+	}
+	if len(ids) > 0 {
+		slices.Sort(ids)
+		if err := cst.AddComment(1, restoredComment(ids, line)); err != nil {
+			return nil, false, err
+		}
+	}
+	updated := cst.Bytes()
+	if bytes.Equal(updated, content) {
+		return content, false, nil
+	}
+	return updated, true, nil
+}
+
+// stripRestoredComment removes restoredComment from the comment block at
+// the top of the file, returning the tags it named.
+func stripRestoredComment(cst *parser.CST) []int64 {
+	var ids []int64
+	for n := 1; n <= len(cst.Lines); {
+		l := cst.Lines[n-1]
+		if l.Kind == parser.BlankLine {
+			n++
+			continue
+		}
+		if l.Kind != parser.CommentLine {
+			break
+		}
+		m := restoredCommentRe.FindStringSubmatch(strings.TrimSpace(l.Text))
+		if m == nil {
+			n++
+			continue
+		}
+		if err := cst.RemoveComment(n); err != nil {
+			break
+		}
+		for _, tag := range strings.Fields(m[1]) {
+			if id, err := strconv.ParseInt(strings.TrimPrefix(tag, "@ft:"), 10, 64); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// hasRestoredComment reports whether restoredComment is at the top of a
+// file's content.
+func hasRestoredComment(content []byte) bool {
+	return len(stripRestoredComment(parser.ParseCST(content))) > 0
+}
+
+// fileTags returns the @ft ids tagged on the scenarios of a file's content.
+func fileTags(content []byte) map[int64]bool {
+	doc, errs := parser.Parse("", content)
+	pf := parser.Transform(doc, "", content, errs)
+	tags := make(map[int64]bool)
+	for _, ps := range append(pf.Scenarios, pf.Skipped...) {
+		if id, err := strconv.ParseInt(ps.FtTag, 10, 64); err == nil {
+			tags[id] = true
+		}
+	}
+	return tags
+}
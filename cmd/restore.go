@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreFile string
+	restoreWait time.Duration
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <id> | --file <path>",
+	Short: "Write a scenario or a whole file back from the database",
+	Long: `Reconstruct scenarios from the content stored in fts/ft.db, each under
+its @ft:<id> tag. ft restore <id> writes one scenario back into its file;
+ft restore --file <path> writes back every scenario of the file that's
+missing from it. A deleted file is recreated from its stored Feature:
+header. No status is recorded; the next ft sync picks the scenarios up by
+their tags. Like ft sync, it takes the sync lock, so it never writes a
+file while a sync or a pass of ft watch is.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if restoreFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if restoreFile != "" {
+			return RunRestoreFile(cmd.OutOrStdout(), restoreFile, restoreWait)
+		}
+		return RunRestore(cmd.OutOrStdout(), args[0], restoreWait)
+	},
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreFile, "file", "", "restore the scenarios missing from a file, recreating it if it was deleted")
+	restoreCmd.Flags().DurationVar(&restoreWait, "wait", 0, "when a sync is running, wait up to this long for it to finish instead of failing")
+	rootCmd.AddCommand(restoreCmd)
+}
+
+// RunRestore writes scenario rawID back into its file, waiting up to wait
+// for a running sync to release the sync lock.
+func RunRestore(w io.Writer, rawID string, wait time.Duration) error {
+	rawID = strings.TrimPrefix(rawID, "@ft:")
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid scenario ID: %s", rawID)
+	}
+
+	lock, err := acquireSyncLock(wait)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	store, err := db.OpenProjectStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	detail, err := store.ScenarioDetail(id)
+	if err != nil {
+		return fmt.Errorf("scenario %d not found", id)
+	}
+	rec := db.ScenarioRecord{ID: detail.ID, Name: detail.Name, Rule: detail.Rule, Content: detail.Content, Tags: detail.Tags}
+	return restoreScenarios(w, store, detail.FileID, detail.FilePath, []db.ScenarioRecord{rec})
+}
+
+// RunRestoreFile writes the scenarios missing from the file at path back
+// into it, waiting up to wait for a running sync to release the sync lock.
+func RunRestoreFile(w io.Writer, path string, wait time.Duration) error {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if !strings.HasPrefix(path, "fts/") {
+		path = "fts/" + path
+	}
+
+	lock, err := acquireSyncLock(wait)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	store, err := db.OpenProjectStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	fileID, found, err := store.FindActiveFileID(path)
+	if err != nil {
+		return fmt.Errorf("querying %s: %w", path, err)
+	}
+	if !found {
+		if fileID, found, err = store.FindDeletedFileID(path); err != nil {
+			return fmt.Errorf("querying %s: %w", path, err)
+		}
+	}
+	if !found {
+		return fmt.Errorf("file %s not found", path)
+	}
+
+	byID, err := store.ScenariosByFile(fileID)
+	if err != nil {
+		return fmt.Errorf("querying scenarios of %s: %w", path, err)
+	}
+	scenarios := make([]db.ScenarioRecord, 0, len(byID))
+	for _, rec := range byID {
+		scenarios = append(scenarios, rec)
+	}
+	sort.Slice(scenarios, func(i, j int) bool { return scenarios[i].ID < scenarios[j].ID })
+	if len(scenarios) == 0 {
+		return fmt.Errorf("%s has no scenarios to restore", path)
+	}
+	return restoreScenarios(w, store, fileID, path, scenarios)
+}
+
+// restoreScenarios writes the given scenarios back into the file at path,
+// skipping any whose tag is already in it, or recreates the file from its
+// stored header if it's gone.
+func restoreScenarios(w io.Writer, store *db.Store, fileID int64, path string, scenarios []db.ScenarioRecord) error {
+	return store.WithTx(func(tx *db.Store) error {
+		content, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			present := fileTags(content)
+			var missing []db.ScenarioRecord
+			for _, rec := range scenarios {
+				if !present[rec.ID] {
+					missing = append(missing, rec)
+				}
+			}
+			if len(missing) == 0 {
+				if len(scenarios) == 1 {
+					return fmt.Errorf("@ft:%d is already in %s", scenarios[0].ID, path)
+				}
+				return fmt.Errorf("every scenario of %s is already in it", path)
+			}
+			scenarios = missing
+			content = appendScenarios(content, scenarios)
+		case os.IsNotExist(err):
+			header, err := tx.FileContent(fileID)
+			if err != nil {
+				return fmt.Errorf("querying %s: %w", path, err)
+			}
+			content = rebuildFile(path, header.String, scenarios)
+		default:
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		if err := tx.UndeleteFile(fileID); err != nil {
+			return fmt.Errorf("undeleting %s: %w", path, err)
+		}
+
+		ui.RstLine(w, path)
+		for _, rec := range scenarios {
+			ui.ScenarioLine(w, rec.ID, rec.Name)
+		}
+		return tx.AfterCommit(func() error {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			return writeFileAtomic(path, content)
+		})
+	})
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/db/dbtest"
+)
+
+func runRestore(t *testing.T, id string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, RunRestore(&buf, id, 0))
+	return buf.String()
+}
+
+func runRestoreFile(t *testing.T, path string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, RunRestoreFile(&buf, path, 0))
+	return buf.String()
+}
+
+// @ft:287
+func TestSync_StoresFileHeader(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Users sign in with a password.
+
+  Background:
+    Given the app is running
+
+  @smoke
+  Scenario: User logs in
+    Given a user
+`), 0o644))
+	runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "Feature: Login\n  Users sign in with a password.\n\n  Background:\n    Given the app is running", fx.FileContent("fts/login.ft").String)
+}
+
+// @ft:288
+func TestRestore_ScenarioIntoExistingFile(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n    When they log out\n")
+	runStatusUpdate(t, "2", "accepted")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	runSync(t)
+
+	out := runRestore(t, "@ft:2")
+
+	assert.Contains(t, out, "rst  fts/login.ft\n       + @ft:2 User logs out")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n\n  @ft:2\n  Scenario: User logs out\n    Given a user\n    When they log out\n", string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "removed", fx.LatestStatusByID(2), "restore records no status")
+	fx.Close()
+
+	runSync(t)
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "restored", fx.LatestStatusByID(2))
+}
+
+// @ft:289
+func TestRestore_DeletedFile(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	original := `Feature: Login
+  Users sign in with a password.
+
+  Background:
+    Given the app is running
+
+  @ft:1
+  Scenario: User logs in
+    Given a user
+
+  @ft:2
+  Scenario: User logs out
+    Given a user
+`
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(original), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "1", "accepted")
+	runStatusUpdate(t, "2", "accepted")
+	require.NoError(t, os.Remove("fts/login.ft"))
+	runSync(t)
+
+	out := runRestoreFile(t, "login.ft")
+
+	assert.Contains(t, out, "rst  fts/login.ft\n       + @ft:1 User logs in\n       + @ft:2 User logs out")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.False(t, fx.FileDeleted("fts/login.ft"))
+}
+
+// @ft:290
+func TestRestore_ScenarioIntoItsRule(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+
+  Rule: Passwords
+
+    Scenario: Strong password
+      Given a user
+
+    Scenario: Weak password
+      Given a user
+
+  Rule: Sessions
+
+    Scenario: Session expires
+      Given a user
+`)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+
+  Rule: Passwords
+
+    @ft:1
+    Scenario: Strong password
+      Given a user
+
+  Rule: Sessions
+
+    @ft:3
+    Scenario: Session expires
+      Given a user
+`), 0o644))
+	runStatusUpdate(t, "2", "accepted")
+	runSync(t)
+
+	runRestore(t, "2")
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, `Feature: Login
+
+  Rule: Passwords
+
+    @ft:1
+    Scenario: Strong password
+      Given a user
+
+    @ft:2
+    Scenario: Weak password
+      Given a user
+
+  Rule: Sessions
+
+    @ft:3
+    Scenario: Session expires
+      Given a user
+`, string(data))
+}
+
+// @ft:291
+func TestRestore_RejectsScenarioAlreadyInFile(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+
+	var buf bytes.Buffer
+	assert.EqualError(t, RunRestore(&buf, "1", 0), "@ft:1 is already in fts/login.ft")
+	assert.EqualError(t, RunRestore(&buf, "9", 0), "scenario 9 not found")
+}
+
+// @ft:343
+func TestRestore_TakesTheSyncLock(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n"), 0o644))
+	lock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = RunRestore(&buf, "1", 0)
+	require.ErrorIs(t, err, db.ErrLocked)
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login\n", string(data), "nothing is written while a sync runs")
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		lock.Unlock()
+	}()
+	require.NoError(t, RunRestoreFile(&buf, "fts/login.ft", 5*time.Second))
+
+	data, err = os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "@ft:1")
+	assert.NoFileExists(t, "fts/sync.lock")
+}
+
+// @ft:347
+func TestRestore_KeepsOtherTags(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  @smoke @slow\n  Scenario: User logs out\n    Given a user\n")
+	runStatusUpdate(t, "2", "accepted")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	runSync(t)
+
+	runRestore(t, "2")
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n\n  @ft:2 @smoke @slow\n  Scenario: User logs out\n    Given a user\n", string(data))
+}
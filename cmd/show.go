@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -55,15 +54,19 @@ func RunShow(w io.Writer, rawID string) error {
 	filePath := detail.FilePath
 	storedContent := detail.Content
 
-	fileName := filepath.Base(filePath)
+	fileName := displayPath(filePath)
 
 	// Read and parse the file
 	var scenarioContent string
 	var background string
+	var ruleLine string
+	var ruleBackground string
+	lang := parser.LanguageFor(parser.DefaultLanguage)
 	content, readErr := os.ReadFile(filePath)
 	if readErr == nil {
 		doc, parseErrors := parser.Parse(filePath, content)
 		pf := parser.Transform(doc, filePath, content, parseErrors)
+		lang = parser.LanguageFor(doc.Language)
 
 		// Find the matching scenario by FtTag
 		idStr := strconv.FormatInt(id, 10)
@@ -74,12 +77,24 @@ func RunShow(w io.Writer, rawID string) error {
 			}
 		}
 
-		background = extractBackground(string(content))
+		if doc.Feature.Background != nil {
+			background = extractBackground(lang, string(content), doc.Feature.Background.Line)
+		}
+
+		if rule := ruleContaining(doc, idStr); rule != nil {
+			ruleLine = strings.Split(string(content), "\n")[rule.Line-1]
+			if rule.Background != nil {
+				ruleBackground = extractBackground(lang, string(content), rule.Background.Line)
+			}
+		}
 	}
 
 	// Fall back to stored content for removed scenarios
 	if scenarioContent == "" && storedContent.Valid {
 		scenarioContent = storedContent.String
+		if detail.Rule != "" {
+			ruleLine = lang.Rule[0] + ": " + detail.Rule
+		}
 	}
 
 	if scenarioContent == "" {
@@ -128,12 +143,21 @@ func RunShow(w io.Writer, rawID string) error {
 	// Print Background if present
 	if background != "" {
 		fmt.Fprintln(w)
-		ui.ShowGherkin(w, background)
+		ui.ShowGherkin(w, lang, background)
+	}
+
+	// Print the enclosing Rule and its Background if present
+	if ruleLine != "" {
+		fmt.Fprintln(w)
+		ui.ShowGherkin(w, lang, ruleLine)
+		if ruleBackground != "" {
+			ui.ShowGherkin(w, lang, ruleBackground)
+		}
 	}
 
 	// Print scenario content
 	fmt.Fprintln(w)
-	ui.ShowGherkin(w, scenarioContent)
+	ui.ShowGherkin(w, lang, scenarioContent)
 
 	return nil
 }
@@ -177,36 +201,39 @@ func RunShowHistory(w io.Writer, rawID string) error {
 	return nil
 }
 
-// extractBackground finds the Background: section in raw file content
-// and returns it as a string, collecting lines until the next keyword or tag.
-func extractBackground(content string) string {
-	lines := strings.Split(content, "\n")
-	inBackground := false
-	var bgLines []string
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "Background:") {
-			inBackground = true
-			bgLines = append(bgLines, line)
-			continue
-		}
-		if inBackground {
-			// Stop at next keyword, tag, or scenario
-			if strings.HasPrefix(trimmed, "Scenario:") ||
-				strings.HasPrefix(trimmed, "Scenario Outline:") ||
-				strings.HasPrefix(trimmed, "@") ||
-				strings.HasPrefix(trimmed, "Rule:") ||
-				strings.HasPrefix(trimmed, "Examples:") {
-				break
+// ruleContaining returns the Rule: whose scenarios include the one tagged
+// @ft:<idStr>, or nil if the scenario isn't inside a rule.
+func ruleContaining(doc *parser.Document, idStr string) *parser.Rule {
+	for i := range doc.Feature.Rules {
+		for _, sd := range doc.Feature.Rules[i].Scenarios {
+			for _, tag := range sd.Tags {
+				if tag.Name == "@ft:"+idStr {
+					return &doc.Feature.Rules[i]
+				}
 			}
-			bgLines = append(bgLines, line)
 		}
 	}
+	return nil
+}
 
-	if len(bgLines) == 0 {
+// extractBackground returns the Background: section whose keyword is on the
+// given 1-based line of the raw file content, collecting lines until the
+// next keyword or tag.
+func extractBackground(lang *parser.Language, content string, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
 		return ""
 	}
+	bgLines := []string{lines[line-1]}
+
+	for _, l := range lines[line:] {
+		trimmed := strings.TrimSpace(l)
+		// Stop at next keyword, tag, or scenario
+		if lang.IsKeyword(trimmed) || strings.HasPrefix(trimmed, "@") {
+			break
+		}
+		bgLines = append(bgLines, l)
+	}
 
 	// Trim trailing blank lines
 	for len(bgLines) > 0 && strings.TrimSpace(bgLines[len(bgLines)-1]) == "" {
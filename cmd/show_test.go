@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -169,6 +170,64 @@ func TestShow_ContentFromMultiScenarioFile(t *testing.T) {
 	assert.NotContains(t, out2, "User logs in")
 }
 
+// @ft:243
+func TestShow_ScenarioOutlineIncludesExamples(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Examples:
+      | role  |
+      | admin |
+`), 0o644))
+	runSync(t)
+
+	out := runShow(t, "1")
+
+	assert.Contains(t, out, "Scenario Outline:")
+	assert.Contains(t, out, "User logs in as <role>")
+	assert.Contains(t, out, "Examples:")
+	assert.Contains(t, out, "| admin |")
+}
+
+// @ft:248
+func TestShow_IncludesRuleAndRuleBackground(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/refunds.ft", []byte(`Feature: Refunds
+  Background:
+    Given a customer
+
+  Rule: Refunds need a receipt
+    Background:
+      Given a purchase
+
+    Scenario: Refund with receipt
+      Given a receipt
+
+  Rule: Refunds expire
+    Background:
+      Given an old purchase
+
+    Scenario: Refund after 30 days
+      Given 30 days have passed
+`), 0o644))
+	runSync(t)
+
+	out := runShow(t, "1")
+
+	assert.Contains(t, out, "Given a customer")
+	assert.Contains(t, out, "Rule: Refunds need a receipt")
+	assert.Contains(t, out, "Given a purchase")
+	assert.Contains(t, out, "Refund with receipt")
+	assert.NotContains(t, out, "Refunds expire")
+	assert.NotContains(t, out, "Given an old purchase")
+	assert.Less(t, strings.Index(out, "Given a customer"), strings.Index(out, "Rule: Refunds need a receipt"))
+	assert.Less(t, strings.Index(out, "Given a purchase"), strings.Index(out, "Scenario: Refund with receipt"))
+}
+
 // @ft:56
 func TestShow_IncludesBackgroundSection(t *testing.T) {
 	inTempDir(t)
@@ -293,3 +352,32 @@ func TestShow_RemovedScenarioUsesStoredContent(t *testing.T) {
 	assert.Contains(t, out, "Then  the user sees the dashboard")
 	assert.Contains(t, out, "removed")
 }
+
+// @ft:250
+func TestShow_LocalizedBackgroundAndRule(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/reembolsos.ft", []byte(`# language: es
+Característica: Reembolsos
+  Antecedentes:
+    Dado un cliente
+
+  Regla: Los reembolsos requieren recibo
+    Antecedentes:
+      Dado una compra
+
+    Escenario: Reembolso con recibo
+      Cuando pide un reembolso
+      Entonces recibe el dinero
+`), 0o644))
+	runSync(t)
+
+	out := runShow(t, "1")
+
+	assert.Contains(t, out, "Dado un cliente")
+	assert.Contains(t, out, "Dado una compra")
+	assert.Contains(t, out, "Escenario: Reembolso con recibo")
+	assert.Equal(t, 1, strings.Count(out, "Regla: Los reembolsos requieren recibo"))
+	assert.Less(t, strings.Index(out, "Dado un cliente"), strings.Index(out, "Regla:"))
+	assert.Less(t, strings.Index(out, "Dado una compra"), strings.Index(out, "Escenario:"))
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/parser"
+)
+
+// defaultMatchThreshold is the similarity a scenario without a known tag
+// needs to be matched to a stored scenario that's missing from its file.
+const defaultMatchThreshold = 0.7
+
+// fuzzyMatch pairs a scenario without a known tag with a stored one.
+type fuzzyMatch struct {
+	pending int // index into the pending scenarios
+	id      int64
+	score   float64
+}
+
+// matchSimilar pairs scenarios that matched neither by tag nor by name with
+// the candidate stored scenarios they most resemble, best pairs first. Each
+// scenario takes part in one match at most, and a pair must score at least
+// threshold.
+func matchSimilar(pending []parser.ParsedScenario, candidates map[int64]db.ScenarioRecord, threshold float64) []fuzzyMatch {
+	var pairs []fuzzyMatch
+	for i, ps := range pending {
+		for id, rec := range candidates {
+			if score := scenarioSimilarity(ps, rec); score >= threshold {
+				pairs = append(pairs, fuzzyMatch{pending: i, id: id, score: score})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.pending != b.pending {
+			return a.pending < b.pending
+		}
+		return a.id < b.id
+	})
+
+	var matches []fuzzyMatch
+	usedPending := make(map[int]bool)
+	usedID := make(map[int64]bool)
+	for _, p := range pairs {
+		if usedPending[p.pending] || usedID[p.id] {
+			continue
+		}
+		usedPending[p.pending] = true
+		usedID[p.id] = true
+		matches = append(matches, p)
+	}
+	return matches
+}
+
+// scenarioSimilarity scores how alike a parsed scenario and a stored one
+// are, from 0 to 1. Steps count twice as much as the name, and both are
+// compared word by word after the same normalisation change detection uses,
+// so reformatting doesn't lower the score.
+func scenarioSimilarity(ps parser.ParsedScenario, rec db.ScenarioRecord) float64 {
+	name := wordSimilarity(strings.Fields(strings.ToLower(ps.Name)), strings.Fields(strings.ToLower(rec.Name)))
+	if !rec.Content.Valid {
+		return name
+	}
+	a := strings.Fields(strings.ToLower(stepsOf(ps.Content)))
+	b := strings.Fields(strings.ToLower(stepsOf(rec.Content.String)))
+	if len(a) == 0 && len(b) == 0 {
+		return name
+	}
+	return (name + 2*wordSimilarity(a, b)) / 3
+}
+
+// wordSimilarity returns twice the length of the longest common subsequence
+// of a and b over their combined length: 1 for equal sequences, 0 for
+// sequences without a word in common.
+func wordSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return float64(2*prev[len(b)]) / float64(len(a)+len(b))
+}
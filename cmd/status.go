@@ -89,5 +89,17 @@ func RunStatusReport(w io.Writer) error {
 		}
 	}
 
+	// Tests still tagged with a removed scenario test a dead spec
+	orphans, err := store.OrphanedTestLinks()
+	if err != nil {
+		return fmt.Errorf("querying test links: %w", err)
+	}
+	if len(orphans) > 0 {
+		fmt.Fprintf(w, "Orphaned test links: %d\n", len(orphans))
+		for _, l := range orphans {
+			ui.OrphanedLinkLine(w, l.FilePath, l.LineNumber, l.ScenarioID, l.Name)
+		}
+	}
+
 	return nil
 }
@@ -266,7 +266,7 @@ func TestList_FiltersByStatusArg(t *testing.T) {
 	runStatusUpdate(t, "2", "in-progress")
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, []string{"accepted"}, nil))
+	require.NoError(t, RunList(&buf, []string{"accepted"}, nil, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User logs in")
@@ -282,7 +282,7 @@ func TestList_FiltersByNoActivityNegation(t *testing.T) {
 	runStatusUpdate(t, "1", "accepted")
 
 	var buf bytes.Buffer
-	require.NoError(t, RunList(&buf, []string{"no-activity"}, nil))
+	require.NoError(t, RunList(&buf, []string{"no-activity"}, nil, ""))
 	out := buf.String()
 
 	assert.Contains(t, out, "User fails login")
@@ -340,7 +340,7 @@ func TestList_FilterNoMatchesReturnsEmpty(t *testing.T) {
 	runStatusUpdate(t, "1", "accepted")
 
 	var buf bytes.Buffer
-	err := RunList(&buf, []string{"done"}, nil)
+	err := RunList(&buf, []string{"done"}, nil, "")
 
 	require.NoError(t, err)
 	assert.Empty(t, buf.String())
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"go/scanner"
 	"go/token"
@@ -9,37 +11,147 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chriserin/ft/internal/db"
 	"github.com/chriserin/ft/internal/parser"
-	"github.com/chriserin/ft/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var (
+	syncWriteErrors bool
+	syncDryRun      bool
+	syncCheck       bool
+	syncForce       bool
+	syncFixDups     bool
+	syncThreshold   float64
+	syncFull        bool
+	syncWait        time.Duration
+	syncSince       string
+	syncChanged     string
+	syncJSON        bool
+)
+
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Scan fts/ for .ft files and register new ones",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return RunSync(cmd.OutOrStdout())
+		if err := validateMatchThreshold(syncThreshold); err != nil {
+			return err
+		}
+		opts := SyncOptions{WriteErrors: syncWriteErrors, DryRun: syncDryRun, Check: syncCheck, Force: syncForce, FixDuplicates: syncFixDups, MatchThreshold: syncThreshold, Full: syncFull, Wait: syncWait, JSON: syncJSON}
+		var err error
+		switch {
+		case cmd.Flags().Changed("since"):
+			opts.OnlyChanged = true
+			opts.Changed, err = changedSince(syncSince)
+		case cmd.Flags().Changed("changed-files"):
+			opts.OnlyChanged = true
+			opts.Changed, err = readChangedFiles(syncChanged, cmd.InOrStdin())
+		}
+		// The flags parsed: whatever fails from here isn't a usage error
+		cmd.SilenceUsage = true
+		if err != nil {
+			return err
+		}
+		return RunSync(cmd.OutOrStdout(), opts)
 	},
 }
 
 func init() {
+	syncCmd.Flags().BoolVar(&syncWriteErrors, "write-errors", false, "also write parse errors as # ft error: comments at the top of the file")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "show what sync would change without writing the database or any file")
+	syncCmd.Flags().BoolVar(&syncCheck, "check", false, "like --dry-run, but exit non-zero if sync would change anything")
+	syncCmd.Flags().BoolVar(&syncForce, "force", false, "remove scenarios even if tests still link to them")
+	syncCmd.Flags().BoolVar(&syncFixDups, "fix-duplicates", false, "give scenarios copied with another scenario's @ft tag a new id")
+	syncCmd.Flags().Float64Var(&syncThreshold, "match-threshold", defaultMatchThreshold, "similarity (0-1] an edited scenario without a tag needs to keep a missing scenario's id")
+	syncCmd.Flags().BoolVar(&syncFull, "full", false, "re-read every .ft and test file, even those unchanged since the last sync")
+	syncCmd.Flags().DurationVar(&syncWait, "wait", 0, "when another sync is running, wait up to this long for it to finish instead of failing")
+	syncCmd.Flags().StringVar(&syncSince, "since", "", "only reconcile .ft and test files git reports changed since this ref")
+	syncCmd.Flags().StringVar(&syncChanged, "changed-files", "", "only reconcile the .ft and test files listed in this file, one per line (- for stdin), e.g. from git diff --name-only --relative")
+	syncCmd.Flags().BoolVar(&syncJSON, "json", false, "print what sync does as JSON events, one per line")
+	syncCmd.MarkFlagsMutuallyExclusive("since", "changed-files", "full")
 	rootCmd.AddCommand(syncCmd)
 }
 
+// SyncOptions controls optional RunSync behavior.
+type SyncOptions struct {
+	// WriteErrors writes each parse error into its file as a
+	// "# ft error:" comment, in addition to recording it as a diagnostic.
+	WriteErrors bool
+	// DryRun reports what the sync would change, then rolls it back
+	// without writing the database, fts/statuses.csv or any .ft file.
+	DryRun bool
+	// Check is DryRun that also fails with errOutOfSync when the sync
+	// would change anything, e.g. in CI.
+	Check bool
+	// Force removes scenarios that tests still link to instead of
+	// writing them back into their files.
+	Force bool
+	// FixDuplicates gives each copy of a scenario's @ft tag a fresh id
+	// instead of reporting it as an error and leaving it unsynced.
+	FixDuplicates bool
+	// MatchThreshold is the similarity, from 0 to 1, a scenario without a
+	// known tag needs to be matched to a stored scenario missing from its
+	// file by name and steps. Zero means defaultMatchThreshold.
+	MatchThreshold float64
+	// Full re-reads and reconciles every .ft file and rescans every test
+	// file, instead of skipping those unchanged since the last sync.
+	Full bool
+	// Wait is how long to wait for another sync holding the sync lock to
+	// finish. Zero fails straight away, unless ft watch holds the lock.
+	Wait time.Duration
+	// OnlyChanged limits the pass to the files in Changed: the other .ft
+	// and test files sync already knows are taken as unchanged without
+	// being looked at. Deleted files are still found.
+	OnlyChanged bool
+	// Changed lists the changed files for OnlyChanged, relative to the
+	// project root.
+	Changed []string
+	// Quiet leaves out the trk lines of files the pass left as they were,
+	// and the summary if it changed nothing, as ft watch logs its passes.
+	Quiet bool
+	// JSON reports the pass as JSON events, one per line, instead of
+	// marker lines: see FT_SYNC.md.
+	JSON bool
+}
+
+// validateMatchThreshold checks a --match-threshold value. Unlike an unset
+// SyncOptions.MatchThreshold, a 0 given on the command line is an error
+// rather than the default.
+func validateMatchThreshold(threshold float64) error {
+	if !(threshold > 0 && threshold <= 1) {
+		return fmt.Errorf("--match-threshold must be greater than 0 and at most 1, got %g", threshold)
+	}
+	return nil
+}
+
+func (o SyncOptions) matchThreshold() float64 {
+	if o.MatchThreshold == 0 {
+		return defaultMatchThreshold
+	}
+	return o.MatchThreshold
+}
+
 type tagInsertion struct {
-	line int   // 1-based line number of the Scenario: line
-	id   int64 // scenario ID
+	line    int   // 1-based line number of the Scenario: line
+	id      int64 // scenario ID
+	replace int64 // @ft id of a duplicate tag on the scenario to replace, or 0
 }
 
 type scenarioAction struct {
-	kind string // "new", "modified", "removed", "unchanged"
-	id   int64
-	name string
+	kind     string // "new", "restored", "modified", "matched", "moved", "removed", "rehydrated", "retagged", "unchanged"
+	id       int64
+	name     string
+	from     string            // previous file of a moved scenario
+	stored   db.ScenarioRecord // stored scenario written back by a rehydration
+	was      int64             // duplicate @ft id a retagged scenario carried
+	prevName string            // stored name of a scenario matched by similarity
+	score    float64           // similarity of a matched scenario, 0 to 1
 }
 
 func stepsOf(content string) string {
@@ -54,7 +166,8 @@ var docStringDelimRe = regexp.MustCompile("^(\"\"\"|```)")
 
 // normalizeSteps neutralizes whitespace-only differences introduced by
 // reformatting (reindentation, step-keyword padding, table column
-// alignment) so that only real text edits register as a content change.
+// alignment and cell padding, as done by ft fmt) so that only real text
+// edits register as a content change.
 // Lines inside a doc string (delimited by """ or ```) are left alone
 // except for a shared leading indent stripped from the whole block, since
 // relative alignment inside a doc string can be meaningful.
@@ -105,6 +218,10 @@ func normalizeSteps(content string) string {
 			docBlock = append(docBlock, line)
 			continue
 		}
+		if strings.HasPrefix(trimmed, "|") {
+			out = append(out, normalizeTableRow(trimmed))
+			continue
+		}
 		out = append(out, strings.Join(strings.Fields(line), " "))
 	}
 	flushDocBlock()
@@ -112,6 +229,21 @@ func normalizeSteps(content string) string {
 	return strings.Join(out, "\n")
 }
 
+// normalizeTableRow rewrites a table row with one space of padding around
+// each cell, so "|a|b|" and "| a | b |" compare equal.
+func normalizeTableRow(trimmed string) string {
+	cells, trailing := parser.SplitTableRow(trimmed)
+	var b strings.Builder
+	b.WriteString("|")
+	for _, cell := range cells {
+		b.WriteString(" " + strings.Join(strings.Fields(cell), " ") + " |")
+	}
+	if trailing != "" {
+		b.WriteString(" " + strings.Join(strings.Fields(trailing), " "))
+	}
+	return b.String()
+}
+
 // insertOrAdoptScenario inserts a scenario belonging to a file not yet
 // tracked in the DB. If the scenario already carries an @ft:<id> tag and
 // that id isn't claimed by an existing scenario, it's adopted as-is — this
@@ -122,24 +254,104 @@ func normalizeSteps(content string) string {
 func insertOrAdoptScenario(store *db.Store, fileID int64, ps parser.ParsedScenario) (id int64, adopted bool, err error) {
 	if ps.FtTag != "" {
 		if tagID, parseErr := strconv.ParseInt(ps.FtTag, 10, 64); parseErr == nil && !store.ScenarioExists(tagID) {
-			if err := store.InsertScenarioWithID(tagID, fileID, ps.Name, ps.Content); err != nil {
+			if err := store.InsertScenarioWithID(tagID, fileID, ps.Name, ps.Rule, ps.Content); err != nil {
 				return 0, false, err
 			}
 			return tagID, true, nil
 		}
 	}
 
-	id, err = store.InsertScenario(fileID, ps.Name, ps.Content)
+	id, err = store.InsertScenario(fileID, ps.Name, ps.Rule, ps.Content)
 	return id, false, err
 }
 
-func reconcileTrackedFile(store *db.Store, fileID int64, pf *parser.ParsedFile) ([]scenarioAction, []tagInsertion, error) {
+// tagOwners maps each @ft:<id> tag found in this sync's files to the file it
+// appears in. Copies of a tag are set aside before this, so each tag
+// appears once.
+func tagOwners(paths []string, parsed map[string]*parser.ParsedFile) map[int64]string {
+	owners := make(map[int64]string)
+	for _, path := range paths {
+		for _, ps := range append(parsed[path].Scenarios, parsed[path].Skipped...) {
+			if id, err := strconv.ParseInt(ps.FtTag, 10, 64); err == nil {
+				owners[id] = path
+			}
+		}
+	}
+	return owners
+}
+
+// movedOut reports whether the tracked scenario id now lives in a file other
+// than path, so path's reconciliation must leave it for that file to adopt.
+func movedOut(id int64, path string, owners map[int64]string) bool {
+	owner, ok := owners[id]
+	return ok && owner != path
+}
+
+// adoptMovedScenario moves the scenario tagged by ps into fileID when the
+// tag belongs to another file's scenario and path is where it appears now,
+// e.g. after it was cut from one file and pasted into another. The scenario
+// keeps its ID and history; a content change is recorded as usual. Returns
+// the action to report, or ok=false if ps isn't a moved scenario.
+func adoptMovedScenario(store *db.Store, fileID int64, path string, ps parser.ParsedScenario, owners map[int64]string) (scenarioAction, bool, error) {
+	tagID, err := strconv.ParseInt(ps.FtTag, 10, 64)
+	if err != nil || owners[tagID] != path {
+		return scenarioAction{}, false, nil
+	}
+	prev, err := store.ScenarioDetail(tagID)
+	if err != nil || prev.FilePath == path {
+		return scenarioAction{}, false, nil
+	}
+
+	if err := store.MoveScenario(tagID, fileID); err != nil {
+		return scenarioAction{}, false, err
+	}
+	contentChanged := prev.Content.Valid && stepsOf(prev.Content.String) != stepsOf(ps.Content)
+	if err := store.UpdateScenarioNameContent(tagID, ps.Name, ps.Content); err != nil {
+		return scenarioAction{}, false, err
+	}
+	if prev.Rule != ps.Rule {
+		if err := store.UpdateScenarioRule(tagID, ps.Rule); err != nil {
+			return scenarioAction{}, false, err
+		}
+	}
+	if store.LatestInsertedStatusIsRemoved(tagID) {
+		if err := store.InsertStatus(tagID, "restored"); err != nil {
+			return scenarioAction{}, false, err
+		}
+	} else if latest, _ := store.LatestInsertedStatus(tagID); contentChanged && store.HasStatusHistory(tagID) && latest != "modified" {
+		if err := store.InsertStatus(tagID, "modified"); err != nil {
+			return scenarioAction{}, false, err
+		}
+	}
+	return scenarioAction{kind: "moved", id: tagID, name: ps.Name, from: prev.FilePath}, true, nil
+}
+
+func reconcileTrackedFile(store *db.Store, fileID int64, path string, pf *parser.ParsedFile, owners map[int64]string, linked testLinkIndex, force bool, threshold float64) ([]scenarioAction, []tagInsertion, error) {
 	remaining, err := store.ScenariosByFile(fileID)
 	if err != nil {
 		return nil, nil, err
 	}
 	var actions []scenarioAction
 	var insertions []tagInsertion
+	var pending []parser.ParsedScenario // untagged, and matched by no name
+	var pendingAt []int                 // index of each pending scenario's action
+
+	// Scenarios in a broken region keep their DB state until the file is
+	// fixed: they're neither updated nor removed
+	for _, ps := range pf.Skipped {
+		if tagID, err := strconv.ParseInt(ps.FtTag, 10, 64); err == nil {
+			if _, ok := remaining[tagID]; ok {
+				delete(remaining, tagID)
+				continue
+			}
+		}
+		for dbID, dbS := range remaining {
+			if dbS.Name == ps.Name {
+				delete(remaining, dbID)
+				break
+			}
+		}
+	}
 
 	for _, ps := range pf.Scenarios {
 		matched := false
@@ -154,26 +366,43 @@ func reconcileTrackedFile(store *db.Store, fileID int64, pf *parser.ParsedFile)
 
 					wasRemoved := store.LatestInsertedStatusIsRemoved(tagID)
 					if wasRemoved {
-						store.InsertStatus(tagID, "restored")
+						if err := store.InsertStatus(tagID, "restored"); err != nil {
+							return nil, nil, err
+						}
 					}
 
 					nameChanged := dbS.Name != ps.Name
+					ruleChanged := dbS.Rule != ps.Rule
 					contentChanged := dbS.Content.Valid && stepsOf(dbS.Content.String) != stepsOf(ps.Content)
 					firstPopulation := !dbS.Content.Valid
 
+					if ruleChanged {
+						if err := store.UpdateScenarioRule(tagID, ps.Rule); err != nil {
+							return nil, nil, err
+						}
+					}
+
 					if wasRemoved {
-						store.UpdateScenarioNameContent(tagID, ps.Name, ps.Content)
-						actions = append(actions, scenarioAction{kind: "new", id: tagID, name: ps.Name})
-					} else if nameChanged || contentChanged {
-						store.UpdateScenarioNameContent(tagID, ps.Name, ps.Content)
+						if err := store.UpdateScenarioNameContent(tagID, ps.Name, ps.Content); err != nil {
+							return nil, nil, err
+						}
+						actions = append(actions, scenarioAction{kind: "restored", id: tagID, name: ps.Name})
+					} else if nameChanged || ruleChanged || contentChanged {
+						if err := store.UpdateScenarioNameContent(tagID, ps.Name, ps.Content); err != nil {
+							return nil, nil, err
+						}
 						latestStatus, _ := store.LatestInsertedStatus(tagID)
 						if contentChanged && store.HasStatusHistory(tagID) && latestStatus != "modified" {
-							store.InsertStatus(tagID, "modified")
+							if err := store.InsertStatus(tagID, "modified"); err != nil {
+								return nil, nil, err
+							}
 						}
 						actions = append(actions, scenarioAction{kind: "modified", id: tagID, name: ps.Name})
 					} else if firstPopulation {
 						// Silently populate content without marking as modified
-						store.UpdateScenarioContent(tagID, ps.Content)
+						if err := store.UpdateScenarioContent(tagID, ps.Content); err != nil {
+							return nil, nil, err
+						}
 						actions = append(actions, scenarioAction{kind: "unchanged", id: tagID, name: ps.Name})
 					} else {
 						actions = append(actions, scenarioAction{kind: "unchanged", id: tagID, name: ps.Name})
@@ -183,6 +412,16 @@ func reconcileTrackedFile(store *db.Store, fileID int64, pf *parser.ParsedFile)
 			}
 		}
 
+		if !matched {
+			// Tagged with the ID of a scenario from another file: moved here
+			if a, ok, err := adoptMovedScenario(store, fileID, path, ps, owners); err != nil {
+				return nil, nil, err
+			} else if ok {
+				actions = append(actions, a)
+				continue
+			}
+		}
+
 		if !matched {
 			// Try name match in remaining
 			nameMatched := false
@@ -191,51 +430,118 @@ func reconcileTrackedFile(store *db.Store, fileID int64, pf *parser.ParsedFile)
 					// Matched by name
 					delete(remaining, dbID)
 					nameMatched = true
-
-					contentChanged := dbS.Content.Valid && stepsOf(dbS.Content.String) != stepsOf(ps.Content)
-
-					store.UpdateScenarioNameContent(dbID, ps.Name, ps.Content)
-					insertions = append(insertions, tagInsertion{line: ps.Line, id: dbID})
-
-					if contentChanged {
-						latestStatus, _ := store.LatestInsertedStatus(dbID)
-						if store.HasStatusHistory(dbID) && latestStatus != "modified" {
-							store.InsertStatus(dbID, "modified")
-						}
+					if err := updateMatchedScenario(store, dbID, dbS, ps); err != nil {
+						return nil, nil, err
 					}
+					insertions = append(insertions, tagInsertion{line: ps.Line, id: dbID})
 					actions = append(actions, scenarioAction{kind: "modified", id: dbID, name: ps.Name})
 					break
 				}
 			}
 
-			if !nameMatched {
+			if !nameMatched && ps.FtTag == "" {
+				// Matched by similarity or new, once every tag and name
+				// match is made; its action is filled in then
+				pending = append(pending, ps)
+				pendingAt = append(pendingAt, len(actions))
+				actions = append(actions, scenarioAction{})
+			} else if !nameMatched {
 				// New scenario
-				id, err := store.InsertScenario(fileID, ps.Name, ps.Content)
-				if err == nil {
-					insertions = append(insertions, tagInsertion{line: ps.Line, id: id})
-					actions = append(actions, scenarioAction{kind: "new", id: id, name: ps.Name})
+				id, err := store.InsertScenario(fileID, ps.Name, ps.Rule, ps.Content)
+				if err != nil {
+					return nil, nil, fmt.Errorf("inserting scenario %q: %w", ps.Name, err)
 				}
+				insertions = append(insertions, tagInsertion{line: ps.Line, id: id})
+				actions = append(actions, scenarioAction{kind: "new", id: id, name: ps.Name})
 			}
 		}
 	}
 
-	// Remaining entries are removed scenarios
+	// An untagged scenario whose name and steps were both edited is matched
+	// to the missing stored scenario it most resembles. One carrying an
+	// unknown tag claims to be something else, so it's new.
+	candidates := make(map[int64]db.ScenarioRecord)
 	for dbID, dbS := range remaining {
-		if store.LatestInsertedStatusIsRemoved(dbID) {
+		if !store.LatestInsertedStatusIsRemoved(dbID) && !movedOut(dbID, path, owners) {
+			candidates[dbID] = dbS
+		}
+	}
+	fuzzy := make(map[int]fuzzyMatch)
+	for _, m := range matchSimilar(pending, candidates, threshold) {
+		fuzzy[m.pending] = m
+	}
+	for i, ps := range pending {
+		if m, ok := fuzzy[i]; ok {
+			dbS := remaining[m.id]
+			delete(remaining, m.id)
+			if err := updateMatchedScenario(store, m.id, dbS, ps); err != nil {
+				return nil, nil, err
+			}
+			insertions = append(insertions, tagInsertion{line: ps.Line, id: m.id})
+			actions[pendingAt[i]] = scenarioAction{kind: "matched", id: m.id, name: ps.Name, prevName: dbS.Name, score: m.score}
+			continue
+		}
+
+		// New scenario
+		id, err := store.InsertScenario(fileID, ps.Name, ps.Rule, ps.Content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("inserting scenario %q: %w", ps.Name, err)
+		}
+		insertions = append(insertions, tagInsertion{line: ps.Line, id: id})
+		actions[pendingAt[i]] = scenarioAction{kind: "new", id: id, name: ps.Name}
+	}
+
+	// Remaining entries are removed scenarios, unless they moved to
+	// another file. One that tests still link to is written back instead,
+	// unless forced.
+	for dbID, dbS := range remaining {
+		if store.LatestInsertedStatusIsRemoved(dbID) || movedOut(dbID, path, owners) {
+			continue
+		}
+		if len(linked[dbID]) > 0 && !force {
+			actions = append(actions, scenarioAction{kind: "rehydrated", id: dbID, name: dbS.Name, stored: dbS})
 			continue
 		}
 		actions = append(actions, scenarioAction{kind: "removed", id: dbID, name: dbS.Name})
-		if store.HasStatusHistory(dbID) {
-			store.InsertStatus(dbID, "removed")
+		if store.HasStatusHistory(dbID) || len(linked[dbID]) > 0 {
+			if err := store.InsertStatus(dbID, "removed"); err != nil {
+				return nil, nil, err
+			}
 		} else {
-			store.DeleteScenario(dbID)
+			if err := store.DeleteScenario(dbID); err != nil {
+				return nil, nil, err
+			}
 		}
 	}
 
 	return actions, insertions, nil
 }
 
-func handleDeletedFile(store *db.Store, fileID int64) ([]scenarioAction, error) {
+// updateMatchedScenario brings the stored scenario dbID up to date with ps,
+// which matched it without a tag. A content change is recorded as usual.
+func updateMatchedScenario(store *db.Store, dbID int64, dbS db.ScenarioRecord, ps parser.ParsedScenario) error {
+	contentChanged := dbS.Content.Valid && stepsOf(dbS.Content.String) != stepsOf(ps.Content)
+	if err := store.UpdateScenarioNameContent(dbID, ps.Name, ps.Content); err != nil {
+		return err
+	}
+	if dbS.Rule != ps.Rule {
+		if err := store.UpdateScenarioRule(dbID, ps.Rule); err != nil {
+			return err
+		}
+	}
+	if contentChanged {
+		latestStatus, _ := store.LatestInsertedStatus(dbID)
+		if store.HasStatusHistory(dbID) && latestStatus != "modified" {
+			return store.InsertStatus(dbID, "modified")
+		}
+	}
+	return nil
+}
+
+// handleDeletedFile removes the scenarios of a file that's gone from disk,
+// except those tests still link to, which unless forced are returned as
+// "rehydrated" for the file to be rebuilt with.
+func handleDeletedFile(store *db.Store, fileID int64, linked testLinkIndex, force bool) ([]scenarioAction, error) {
 	remaining, err := store.ScenariosByFile(fileID)
 	if err != nil {
 		return nil, err
@@ -246,42 +552,349 @@ func handleDeletedFile(store *db.Store, fileID int64) ([]scenarioAction, error)
 		if store.LatestInsertedStatusIsRemoved(dbID) {
 			continue
 		}
+		if len(linked[dbID]) > 0 && !force {
+			actions = append(actions, scenarioAction{kind: "rehydrated", id: dbID, name: dbS.Name, stored: dbS})
+			continue
+		}
 		actions = append(actions, scenarioAction{kind: "removed", id: dbID, name: dbS.Name})
-		if store.HasStatusHistory(dbID) {
-			store.InsertStatus(dbID, "removed")
+		if store.HasStatusHistory(dbID) || len(linked[dbID]) > 0 {
+			if err := store.InsertStatus(dbID, "removed"); err != nil {
+				return nil, err
+			}
 		} else {
-			store.DeleteScenario(dbID)
+			if err := store.DeleteScenario(dbID); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return actions, nil
 }
 
-func RunSync(w io.Writer) error {
+// renameMatch scores how likely it is that the untracked file newPath is the
+// missing tracked file oldID under a new name.
+type renameMatch struct {
+	newPath string
+	oldID   int64
+	oldPath string
+	tags    int // scenarios in newPath carrying an @ft tag of oldPath's scenarios
+	content int // scenarios in newPath with the same name and steps as one of oldPath's
+}
+
+// detectRenames pairs tracked files missing from disk with untracked files
+// that hold their scenarios, and moves each file record to its new path so
+// the scenarios keep their IDs and history. A pair matches on @ft tags, or,
+// for an untagged copy, on at least half of the old file's scenarios having
+// the same name and steps. Returns the renamed files, new path to old path.
+func detectRenames(store *db.Store, paths []string, parsed map[string]*parser.ParsedFile) (map[string]string, error) {
+	active, err := store.ActiveFiles()
+	if err != nil {
+		return nil, err
+	}
+	onDisk := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		onDisk[p] = true
+	}
+	tracked := make(map[string]bool, len(active))
+	var missing []db.FileRecord
+	for _, f := range active {
+		tracked[f.FilePath] = true
+		if !onDisk[f.FilePath] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	oldScenarios := make(map[int64]map[int64]db.ScenarioRecord, len(missing))
+	for _, f := range missing {
+		if oldScenarios[f.ID], err = store.ScenariosByFile(f.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var matches []renameMatch
+	for _, path := range paths {
+		if tracked[path] {
+			continue
+		}
+		// A path with a deleted record is undeleted instead
+		if _, found, err := store.FindDeletedFileID(path); err != nil {
+			return nil, err
+		} else if found {
+			continue
+		}
+		pf := parsed[path]
+		for _, f := range missing {
+			m := renameMatch{newPath: path, oldID: f.ID, oldPath: f.FilePath}
+			old := oldScenarios[f.ID]
+			for _, ps := range append(pf.Scenarios, pf.Skipped...) {
+				if id, err := strconv.ParseInt(ps.FtTag, 10, 64); err == nil {
+					if _, ok := old[id]; ok {
+						m.tags++
+						continue
+					}
+				}
+				for _, dbS := range old {
+					if dbS.Name == ps.Name && dbS.Content.Valid && stepsOf(dbS.Content.String) == stepsOf(ps.Content) {
+						m.content++
+						break
+					}
+				}
+			}
+			if m.tags > 0 || (m.content > 0 && 2*m.content >= len(old)) {
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	// Strongest evidence first; each file takes part in one rename at most
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.tags != b.tags {
+			return a.tags > b.tags
+		}
+		if a.content != b.content {
+			return a.content > b.content
+		}
+		if a.newPath != b.newPath {
+			return a.newPath < b.newPath
+		}
+		return a.oldPath < b.oldPath
+	})
+	renamed := make(map[string]string)
+	claimed := make(map[int64]bool)
+	for _, m := range matches {
+		if _, ok := renamed[m.newPath]; ok || claimed[m.oldID] {
+			continue
+		}
+		if err := store.RenameFile(m.oldID, m.newPath); err != nil {
+			return nil, fmt.Errorf("renaming %s to %s: %w", m.oldPath, m.newPath, err)
+		}
+		renamed[m.newPath] = m.oldPath
+		claimed[m.oldID] = true
+	}
+	return renamed, nil
+}
+
+func RunSync(w io.Writer, opts SyncOptions) error {
+	if !(opts.MatchThreshold >= 0 && opts.MatchThreshold <= 1) {
+		return fmt.Errorf("match threshold must be between 0 and 1, got %g", opts.MatchThreshold)
+	}
+
+	// One sync at a time: two racing would both insert the same new
+	// scenarios and write their tags into the same files. The lock comes
+	// first, as opening the store migrates it
+	lock, err := acquireSyncLock(opts.Wait)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	store, err := db.OpenProjectStore()
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
-	matches, err := filepath.Glob("fts/*.ft")
+	_, err = runSyncPass(w, store, opts)
+	return err
+}
+
+// runSyncPass runs one sync over store, whose caller holds the sync lock,
+// and returns the files it wrote.
+func runSyncPass(w io.Writer, store *db.Store, opts SyncOptions) ([]string, error) {
+	// The whole pass is one transaction, and the tag lines, error comments
+	// and statuses file it writes wait for the commit: a sync that fails
+	// part way leaves both the database and the files as they were. A dry
+	// run is the same pass, rolled back.
+	dryRun := opts.DryRun || opts.Check
+	plan := &syncPlan{}
+	report := newSyncReport(w, opts)
+	var fileCount, scenarioCount int
+	err := store.WithTx(func(tx *db.Store) error {
+		var err error
+		fileCount, scenarioCount, err = syncFiles(report, tx, opts, plan)
+		if err == nil && dryRun {
+			return errDryRun
+		}
+		return err
+	})
+	if !dryRun {
+		if err != nil {
+			return nil, err
+		}
+		if !opts.Quiet || fileCount+scenarioCount > 0 {
+			report.summary(plan, fileCount, scenarioCount, false)
+		}
+		return plan.writes, report.err()
+	}
+	if !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	report.summary(plan, fileCount, scenarioCount, true)
+	if err := report.err(); err != nil {
+		return nil, err
+	}
+	if opts.Check && plan.outOfSync() {
+		return nil, errOutOfSync
+	}
+	return nil, nil
+}
+
+// watchSyncWait is how long a sync waits for a pass of ft watch holding the
+// sync lock, even without --wait.
+const watchSyncWait = 30 * time.Second
+
+// acquireSyncLock takes the sync lock, waiting up to wait for another sync
+// to finish. A pass of ft watch is waited for regardless: it's short, and a
+// manual sync shouldn't fail because a save happened to trigger one.
+// Without fts/ there's nowhere for the lock, so it's db.ErrNotInitialized.
+func acquireSyncLock(wait time.Duration) (*db.Lock, error) {
+	if !db.DataDirExists() {
+		return nil, db.ErrNotInitialized
+	}
+	lock, err := db.AcquireLock(db.SyncLockName, wait)
+	if errors.Is(err, db.ErrLocked) && wait < watchSyncWait && watcherHoldsSyncLock() {
+		lock, err = db.AcquireLock(db.SyncLockName, watchSyncWait)
+	}
+	switch {
+	case errors.Is(err, db.ErrLocked) && wait > 0:
+		return nil, fmt.Errorf("another ft sync is running: %w; gave up after waiting %s", err, wait)
+	case errors.Is(err, db.ErrLocked):
+		return nil, fmt.Errorf("another ft sync is running: %w; retry once it finishes, or pass --wait", err)
+	}
+	return lock, err
+}
+
+// watcherHoldsSyncLock reports whether the sync lock is held by ft watch.
+func watcherHoldsSyncLock() bool {
+	pid := db.LockHolder(db.SyncLockName)
+	return pid != 0 && pid == db.LockHolder(db.WatchLockName)
+}
+
+// syncFiles reconciles every .ft file with the database, returning how many
+// files and scenarios it reported to report. File writes are registered
+// with store.AfterCommit, and every change is recorded in plan.
+func syncFiles(report syncReport, store *db.Store, opts SyncOptions, plan *syncPlan) (int, int, error) {
+	matches, err := discoverFtFiles()
 	if err != nil {
-		return fmt.Errorf("scanning fts/: %w", err)
+		return 0, 0, err
 	}
-	sort.Strings(matches)
+
+	// Parse every changed file up front: renamed files and moved scenarios
+	// are recognised by looking at all of them before any is reconciled.
+	// A file that parsed cleanly last time and hasn't changed since is
+	// skipped, unless opts.Full.
+	stamps, err := store.FileStamps()
+	if err != nil {
+		return 0, 0, fmt.Errorf("querying files: %w", err)
+	}
+	contents := make(map[string][]byte, len(matches))
+	parsed := make(map[string]*parser.ParsedFile, len(matches))
+	infos := make(map[string]fs.FileInfo, len(matches))
+	unchanged := make(map[string]bool)
+	var synced []string // paths parsed and reconciled by this pass
+	parse := func(path string, content []byte) {
+		doc, parseErrors := parser.Parse(path, content)
+		contents[path] = content
+		parsed[path] = parser.Transform(doc, path, content, parseErrors)
+		synced = append(synced, path)
+	}
+	// Files are read and parsed in parallel; the results are applied in
+	// path order so that nothing after this depends on scheduling
+	var changed map[string]bool
+	if opts.OnlyChanged {
+		changed = changedSet(opts.Changed)
+	}
+	reads := parallelMap(matches, func(path string) ftFileRead {
+		st, ok := stamps[path]
+		trusted := ok && !opts.Full && !st.HasDiagnostics
+		if trusted && changed != nil && !changed[path] {
+			return ftFileRead{unchanged: true}
+		}
+		return readFtFile(path, st, trusted)
+	})
+	for i, path := range matches {
+		r := reads[i]
+		if r.err != nil {
+			return 0, 0, fmt.Errorf("reading %s: %w", path, r.err)
+		}
+		infos[path] = r.info
+		if r.unchanged {
+			unchanged[path] = true
+			if r.touched {
+				// Saved without edits: remember its new time
+				st := stamps[path]
+				if err := store.UpdateFileStamp(st.ID, st.Hash, r.info.Size(), stampTime(r.info)); err != nil {
+					return 0, 0, fmt.Errorf("storing %s: %w", path, err)
+				}
+			}
+			continue
+		}
+		contents[path] = r.content
+		parsed[path] = r.parsed
+		synced = append(synced, path)
+	}
+	if len(unchanged) > 0 {
+		if err := reloadTaggedFiles(store, parsed, synced, unchanged, parse); err != nil {
+			return 0, 0, err
+		}
+		sort.Strings(synced)
+	}
+
+	// A tag copied along with its scenario leaves two scenarios claiming
+	// one id. The copies are set aside before anything else looks at the
+	// tags, and either reported or, with --fix-duplicates, given new ids.
+	dups := findDuplicateTags(store, synced, parsed, contents)
+	for path, d := range dups {
+		setAsideDuplicates(parsed[path], d)
+	}
+
+	renamed, err := detectRenames(store, matches, parsed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("detecting renames: %w", err)
+	}
+	owners := tagOwners(synced, parsed)
+	lastStatusID, err := store.LastStatusID()
+	if err != nil {
+		return 0, 0, fmt.Errorf("querying statuses: %w", err)
+	}
+
+	// Scenarios that tests link to aren't dropped when they disappear from
+	// their file; they're written back and reported as conflicts
+	links, err := scanTestLinks(store, opts.Full, changed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("scanning tests: %w", err)
+	}
+	linked := make(testLinkIndex)
+	for _, l := range links {
+		linked[l.scenarioID] = append(linked[l.scenarioID], l)
+	}
+	var conflicts []scenarioAction
+	unfixedDups := false
 
 	diskPaths := make(map[string]bool)
 	fileCount := 0
 	scenarioCount := 0
 	for _, path := range matches {
 		diskPaths[path] = true
+		if unchanged[path] {
+			if !opts.Quiet {
+				report.file("unchanged", path, "")
+				fileCount++
+			}
+			continue
+		}
 
 		// Register file in files table (filter deleted = FALSE)
 		var fileID int64
 		var isNew bool
 		activeID, found, err := store.FindActiveFileID(path)
 		if err != nil {
-			return fmt.Errorf("querying %s: %w", path, err)
+			return 0, 0, fmt.Errorf("querying %s: %w", path, err)
 		}
 		if found {
 			fileID = activeID
@@ -289,164 +902,331 @@ func RunSync(w io.Writer) error {
 			// Check if there's a deleted record to undelete
 			deletedID, foundDeleted, err := store.FindDeletedFileID(path)
 			if err != nil {
-				return fmt.Errorf("querying %s: %w", path, err)
+				return 0, 0, fmt.Errorf("querying %s: %w", path, err)
 			}
 			if foundDeleted {
 				// Undelete
-				store.UndeleteFile(deletedID)
+				if err := store.UndeleteFile(deletedID); err != nil {
+					return 0, 0, fmt.Errorf("undeleting %s: %w", path, err)
+				}
 				fileID = deletedID
 				isNew = false
 			} else {
 				fileID, err = store.InsertFile(path)
 				if err != nil {
-					return fmt.Errorf("inserting %s: %w", path, err)
+					return 0, 0, fmt.Errorf("inserting %s: %w", path, err)
 				}
 				isNew = true
 			}
 		}
 
-		// Read and parse file
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("reading %s: %w", path, err)
-		}
-
-		doc, parseErrors := parser.Parse(path, content)
-		pf := parser.Transform(doc, path, content, parseErrors)
-
-		// If errors, write error comments to file, print err line, skip
-		if len(pf.Errors) > 0 {
-			if err := writeErrorsToFile(path, pf.Errors); err != nil {
-				return fmt.Errorf("writing errors to %s: %w", path, err)
+		pf := parsed[path]
+		content := contents[path]
+		quietTrk := false // left as it was, and not reported with opts.Quiet
+
+		// A fatal error such as an unknown language means nothing in the
+		// file can be trusted, so skip it entirely
+		if pf.Fatal() {
+			if from, ok := renamed[path]; ok {
+				report.file("renamed", path, from)
+				plan.changes++
+			} else if isNew {
+				report.file("new", path, "")
+			} else if opts.Quiet {
+				quietTrk = true
+			} else {
+				report.file("unchanged", path, "")
 			}
-			for _, pe := range pf.Errors {
-				ui.ErrLine(w, path, pe.Message)
+			if content, err = recordParseErrors(report, store, path, content, pf.Errors, opts); err != nil {
+				return 0, 0, err
 			}
-			if isNew {
-				ui.NewLine(w, path)
-			} else {
-				ui.TrkLine(w, path)
+			stageFileWrite(store, plan, path, contents[path], content)
+			if err := recordFileStamp(store, fileID, infos[path], contents[path], content); err != nil {
+				return 0, 0, fmt.Errorf("storing %s: %w", path, err)
+			}
+			if !quietTrk {
+				fileCount++
 			}
-			fileCount++
 			continue
 		}
 
+		wroteTags := false
 		if isNew {
 			// New file path
-			ui.NewLine(w, path)
+			report.file("new", path, "")
 
 			var insertions []tagInsertion
 			for _, ps := range pf.Scenarios {
+				if a, ok, err := adoptMovedScenario(store, fileID, path, ps, owners); err != nil {
+					return 0, 0, fmt.Errorf("moving scenario %q: %w", ps.Name, err)
+				} else if ok {
+					report.scenario(path, a)
+					plan.changes++
+					scenarioCount++
+					continue
+				}
 				id, adopted, err := insertOrAdoptScenario(store, fileID, ps)
 				if err != nil {
-					return fmt.Errorf("inserting scenario %q: %w", ps.Name, err)
+					return 0, 0, fmt.Errorf("inserting scenario %q: %w", ps.Name, err)
 				}
 				if !adopted {
 					insertions = append(insertions, tagInsertion{line: ps.Line, id: id})
 				}
-				ui.ScenarioLine(w, id, ps.Name)
+				report.scenario(path, scenarioAction{kind: "new", id: id, name: ps.Name})
 				scenarioCount++
 			}
+			if opts.FixDuplicates {
+				retagged, ins, err := retagDuplicates(store, fileID, dups[path])
+				if err != nil {
+					return 0, 0, err
+				}
+				for _, a := range retagged {
+					report.scenario(path, a)
+					scenarioCount++
+				}
+				insertions = append(insertions, ins...)
+			}
 
+			if err := recordScenarioTags(store, pf, dups[path], insertions); err != nil {
+				return 0, 0, fmt.Errorf("storing tags of %s: %w", path, err)
+			}
 			if len(insertions) > 0 {
-				if err := writeTagsToFile(path, insertions); err != nil {
-					return fmt.Errorf("writing tags to %s: %w", path, err)
+				if content, err = addTags(content, insertions); err != nil {
+					return 0, 0, fmt.Errorf("writing tags to %s: %w", path, err)
 				}
+				plan.addTags(path, insertions)
+				wroteTags = true
 			}
 		} else {
 			// Tracked file path
-			actions, insertions, err := reconcileTrackedFile(store, fileID, pf)
+			actions, insertions, err := reconcileTrackedFile(store, fileID, path, pf, owners, linked, opts.Force, opts.matchThreshold())
 			if err != nil {
-				return fmt.Errorf("reconciling %s: %w", path, err)
+				return 0, 0, fmt.Errorf("reconciling %s: %w", path, err)
+			}
+			if opts.FixDuplicates {
+				retagged, ins, err := retagDuplicates(store, fileID, dups[path])
+				if err != nil {
+					return 0, 0, err
+				}
+				actions = append(actions, retagged...)
+				insertions = append(insertions, ins...)
 			}
 
 			// Determine mod/trk
 			hasActivity := false
 			for _, a := range actions {
-				if a.kind == "new" || a.kind == "modified" || a.kind == "removed" {
+				if a.kind != "unchanged" {
 					hasActivity = true
 					break
 				}
 			}
 
-			if hasActivity {
-				ui.ModLine(w, path)
+			if from, ok := renamed[path]; ok {
+				report.file("renamed", path, from)
+				plan.changes++
+			} else if hasActivity {
+				report.file("modified", path, "")
+				plan.changes++
+			} else if opts.Quiet {
+				quietTrk = true
 			} else {
-				ui.TrkLine(w, path)
+				report.file("unchanged", path, "")
 			}
 
-			// Print scenario lines
+			// Report scenario lines
 			for _, a := range actions {
-				switch a.kind {
-				case "new":
-					ui.ScenarioLine(w, a.id, a.name)
-					scenarioCount++
-				case "modified":
-					ui.ModifiedScenarioLine(w, a.id, a.name)
-					scenarioCount++
-				case "removed":
-					ui.RemovedScenarioLine(w, a.id, a.name)
+				if a.kind != "unchanged" {
+					report.scenario(path, a)
 					scenarioCount++
 				}
 			}
 
+			if err := recordScenarioTags(store, pf, dups[path], insertions); err != nil {
+				return 0, 0, fmt.Errorf("storing tags of %s: %w", path, err)
+			}
 			if len(insertions) > 0 {
-				if err := writeTagsToFile(path, insertions); err != nil {
-					return fmt.Errorf("writing tags to %s: %w", path, err)
+				if content, err = addTags(content, insertions); err != nil {
+					return 0, 0, fmt.Errorf("writing tags to %s: %w", path, err)
 				}
+				plan.addTags(path, insertions)
+				wroteTags = true
 			}
+			var restored []int64
+			if stored := rehydratedScenarios(actions); len(stored) > 0 {
+				conflicts = append(conflicts, rehydratedActions(actions)...)
+				content = appendScenarios(content, stored)
+				for _, rec := range stored {
+					restored = append(restored, rec.ID)
+				}
+				wroteTags = true
+			}
+			updated, commented, err := updateRestoredComment(content, restored, linked)
+			if err != nil {
+				return 0, 0, fmt.Errorf("restoring scenarios to %s: %w", path, err)
+			}
+			content = updated
+			wroteTags = wroteTags || commented
+		}
+
+		// Scenarios outside the broken regions were synced above; report
+		// the regions themselves. Tag lines written above a region move it
+		// down, so re-parse to report where it is now.
+		parseErrors := pf.Errors
+		if len(parseErrors) > 0 && wroteTags {
+			_, parseErrors = parser.Parse(path, content)
+		}
+		if dupErrors := duplicateErrors(path, content, wroteTags, dups[path], opts.FixDuplicates); len(dupErrors) > 0 {
+			unfixedDups = true
+			parseErrors = append(slices.Clone(parseErrors), dupErrors...)
+			sort.SliceStable(parseErrors, func(i, j int) bool { return parseErrors[i].Line < parseErrors[j].Line })
+		}
+		if content, err = recordParseErrors(report, store, path, content, parseErrors, opts); err != nil {
+			return 0, 0, err
+		}
+		if err := store.UpdateFileContent(fileID, fileHeader(content)); err != nil {
+			return 0, 0, fmt.Errorf("storing %s: %w", path, err)
+		}
+		stageFileWrite(store, plan, path, contents[path], content)
+		if err := recordFileStamp(store, fileID, infos[path], contents[path], content); err != nil {
+			return 0, 0, fmt.Errorf("storing %s: %w", path, err)
+		}
+		if !quietTrk {
+			fileCount++
 		}
-		fileCount++
 	}
 
 	// Handle deleted files
 	allFiles, err := store.ActiveFiles()
 	if err != nil {
-		return fmt.Errorf("querying files: %w", err)
+		return 0, 0, fmt.Errorf("querying files: %w", err)
 	}
 
 	for _, f := range allFiles {
 		if !diskPaths[f.FilePath] {
-			actions, err := handleDeletedFile(store, f.ID)
+			// An ignored file is still on disk, so it isn't rebuilt
+			force := opts.Force
+			if _, err := os.Stat(f.FilePath); err == nil {
+				force = true
+			}
+			actions, err := handleDeletedFile(store, f.ID, linked, force)
 			if err != nil {
-				return fmt.Errorf("handling deleted file %s: %w", f.FilePath, err)
+				return 0, 0, fmt.Errorf("handling deleted file %s: %w", f.FilePath, err)
 			}
 
-			ui.DelLine(w, f.FilePath)
+			// A file with scenarios tests still link to is rebuilt
+			// rather than deleted
+			stored := rehydratedScenarios(actions)
+			if len(stored) > 0 {
+				report.file("restored", f.FilePath, "")
+			} else {
+				report.file("deleted", f.FilePath, "")
+			}
+			plan.changes++
 			fileCount++
 			for _, a := range actions {
-				ui.RemovedScenarioLine(w, a.id, a.name)
+				report.scenario(f.FilePath, a)
 				scenarioCount++
 			}
 
-			store.MarkFileDeleted(f.ID)
+			if len(stored) > 0 {
+				conflicts = append(conflicts, rehydratedActions(actions)...)
+				header, err := store.FileContent(f.ID)
+				if err != nil {
+					return 0, 0, fmt.Errorf("querying %s: %w", f.FilePath, err)
+				}
+				content := rebuildFile(f.FilePath, header.String, stored)
+				stageFileWrite(store, plan, f.FilePath, nil, content)
+				if err := recordFileStamp(store, f.ID, nil, nil, content); err != nil {
+					return 0, 0, fmt.Errorf("storing %s: %w", f.FilePath, err)
+				}
+				diskPaths[f.FilePath] = true
+				continue
+			}
+			if err := store.MarkFileDeleted(f.ID); err != nil {
+				return 0, 0, fmt.Errorf("marking %s deleted: %w", f.FilePath, err)
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].id < conflicts[j].id })
+		for _, c := range conflicts {
+			var tests []string
+			for _, l := range linked[c.id] {
+				tests = append(tests, fmt.Sprintf("%s:%d", l.filePath, l.lineNumber))
+			}
+			report.conflict(c, tests)
 		}
 	}
+	report.hints(len(conflicts) > 0, unfixedDups)
 
-	if err := reconcileStatusesFile(store); err != nil {
-		return fmt.Errorf("reconciling statuses file: %w", err)
+	if err := store.DeleteDiagnosticsExcept(diskPaths); err != nil {
+		return 0, 0, fmt.Errorf("clearing diagnostics: %w", err)
 	}
 
-	if err := syncTestLinks(store); err != nil {
-		return fmt.Errorf("syncing test links: %w", err)
+	// Statuses restored from fts/statuses.csv into a rebuilt database
+	// aren't changes, so collect the pass's statuses before that happens
+	if plan.statuses, err = store.StatusesAfter(lastStatusID); err != nil {
+		return 0, 0, fmt.Errorf("querying statuses: %w", err)
 	}
 
-	ui.SummaryLine(w, fileCount, scenarioCount)
-	return nil
+	if err := reconcileStatusesFile(store, plan); err != nil {
+		return 0, 0, fmt.Errorf("reconciling statuses file: %w", err)
+	}
+
+	if err := syncTestLinks(store, plan, links); err != nil {
+		return 0, 0, fmt.Errorf("syncing test links: %w", err)
+	}
+
+	return fileCount, scenarioCount, nil
+}
+
+// rehydratedScenarios returns the stored scenarios of the "rehydrated"
+// actions.
+func rehydratedScenarios(actions []scenarioAction) []db.ScenarioRecord {
+	var stored []db.ScenarioRecord
+	for _, a := range rehydratedActions(actions) {
+		stored = append(stored, a.stored)
+	}
+	return stored
+}
+
+func rehydratedActions(actions []scenarioAction) []scenarioAction {
+	var rehydrated []scenarioAction
+	for _, a := range actions {
+		if a.kind == "rehydrated" {
+			rehydrated = append(rehydrated, a)
+		}
+	}
+	return rehydrated
+}
+
+// stageFileWrite writes a file's updated content once the sync commits,
+// leaving it untouched if nothing changed. A file rebuilt after being
+// deleted gets its directory back too, should that have been deleted.
+func stageFileWrite(store *db.Store, plan *syncPlan, path string, original, updated []byte) {
+	if bytes.Equal(original, updated) {
+		return
+	}
+	plan.writes = append(plan.writes, path)
+	store.AfterCommit(func() error {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		if err := writeFileAtomic(path, updated); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		return nil
+	})
 }
 
 var ftTagLineRe = regexp.MustCompile(`^\s*@ft:\d+\s*$`)
 
-// writeTagsToFile writes @ft:<id> tag lines into the file above each Scenario: line.
+// addTags adds @ft:<id> tag lines to a file's content above each Scenario: line.
 // If an existing @ft tag line is already above the Scenario:, it is replaced.
 // Modifications are applied from bottom to top to preserve line numbers.
-func writeTagsToFile(path string, insertions []tagInsertion) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-
-	lines := strings.Split(string(data), "\n")
+func addTags(data []byte, insertions []tagInsertion) ([]byte, error) {
+	cst := parser.ParseCST(data)
 
 	// Sort insertions by line number descending so we modify from bottom to top
 	sort.Slice(insertions, func(i, j int) bool {
@@ -454,60 +1234,149 @@ func writeTagsToFile(path string, insertions []tagInsertion) error {
 	})
 
 	for _, ins := range insertions {
-		idx := ins.line - 1 // 0-based index of Scenario: line
-		if idx < 0 || idx >= len(lines) {
-			continue
+		tag := fmt.Sprintf("@ft:%d", ins.id)
+
+		// Replace a duplicate tag wherever it is among the tag lines
+		if ins.replace != 0 {
+			if n, _ := tagPosition(cst.Bytes(), ins.line, ins.replace); n != ins.line {
+				if err := cst.ReplaceTag(n, fmt.Sprintf("@ft:%d", ins.replace), tag); err != nil {
+					return nil, err
+				}
+				continue
+			}
 		}
 
-		// Match indentation of the Scenario: line
-		scenarioLine := lines[idx]
-		var indent strings.Builder
-		for _, ch := range scenarioLine {
-			if ch == ' ' || ch == '\t' {
-				indent.WriteString(string(ch))
-			} else {
-				break
+		// Check if the line above is an existing @ft tag line — replace it
+		if above, err := cst.Line(ins.line - 1); err == nil && ftTagLineRe.MatchString(above.Text) {
+			if err := cst.ReplaceTag(ins.line-1, strings.TrimSpace(above.Text), tag); err != nil {
+				return nil, err
 			}
+			continue
+		}
+		// Insert new tag line above the Scenario: line
+		if err := cst.InsertTag(ins.line, tag); err != nil {
+			return nil, err
 		}
+	}
 
-		tagLine := fmt.Sprintf("%s@ft:%d", indent.String(), ins.id)
+	return cst.Bytes(), nil
+}
 
-		// Check if the line above is an existing @ft tag line — replace it
-		if idx > 0 && ftTagLineRe.MatchString(lines[idx-1]) {
-			lines[idx-1] = tagLine
-		} else {
-			// Insert new tag line above the Scenario: line
-			newLines := make([]string, 0, len(lines)+1)
-			newLines = append(newLines, lines[:idx]...)
-			newLines = append(newLines, tagLine)
-			newLines = append(newLines, lines[idx:]...)
-			lines = newLines
+// recordParseErrors records a file's parse errors as its diagnostics and
+// reports them. With opts.WriteErrors they're also added to the file's
+// content as comments; a file without errors loses any comments an earlier
+// sync wrote. Returns the updated content.
+func recordParseErrors(report syncReport, store *db.Store, path string, content []byte, errors []parser.ParseError, opts SyncOptions) ([]byte, error) {
+	shift := 0
+	if len(errors) == 0 {
+		content = removeErrorComments(content)
+	} else if opts.WriteErrors {
+		var err error
+		if content, shift, err = addErrorComments(content, errors); err != nil {
+			return nil, fmt.Errorf("writing errors to %s: %w", path, err)
 		}
 	}
 
-	tmpPath := path + ".tmp"
-	if err := os.WriteFile(tmpPath, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
-		return err
+	diags := diagnosticsOf(path, errors)
+	for i := range diags {
+		diags[i].Line += shift
+		diags[i].EndLine += shift
 	}
-	return os.Rename(tmpPath, path)
+	if err := store.ReplaceDiagnostics(path, diags); err != nil {
+		return nil, fmt.Errorf("recording diagnostics for %s: %w", path, err)
+	}
+
+	for _, d := range diags {
+		report.diagnostic(d)
+	}
+	return content, nil
 }
 
-// writeErrorsToFile prepends # ft error: comments to the top of the file.
-func writeErrorsToFile(path string, errors []parser.ParseError) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
+// lineRange describes a multi-line region as " (lines a-b)", and returns ""
+// for a single line.
+func lineRange(line, endLine int) string {
+	if endLine <= line {
+		return ""
 	}
+	return fmt.Sprintf(" (lines %d-%d)", line, endLine)
+}
 
-	var errorLines []string
+// diagnosticsOf converts a file's parse errors into diagnostics.
+func diagnosticsOf(path string, errors []parser.ParseError) []db.Diagnostic {
+	diags := make([]db.Diagnostic, 0, len(errors))
 	for _, pe := range errors {
-		errorLines = append(errorLines, fmt.Sprintf("# ft error: %s (line %d)", pe.Message, pe.Line))
+		diags = append(diags, db.Diagnostic{
+			FilePath: path,
+			Line:     pe.Line,
+			EndLine:  max(pe.EndLine, pe.Line),
+			Column:   pe.Column,
+			Severity: "error",
+			Message:  pe.Message,
+		})
+	}
+	return diags
+}
+
+// errorCommentRe matches a comment written by addErrorComments.
+var errorCommentRe = regexp.MustCompile(`^# ft error: .* \(line \d+\)$`)
+
+// stripErrorComments removes the # ft error: comments from the comment
+// block at the top of the file, returning how many it removed. The comment
+// about restored scenarios is left for updateRestoredComment.
+func stripErrorComments(cst *parser.CST) int {
+	removed := 0
+	for n := 1; n <= len(cst.Lines); {
+		l := cst.Lines[n-1]
+		if l.Kind == parser.BlankLine {
+			n++
+			continue
+		}
+		if l.Kind != parser.CommentLine {
+			break
+		}
+		text := strings.TrimSpace(l.Text)
+		if errorCommentRe.MatchString(text) && !restoredCommentRe.MatchString(text) {
+			if err := cst.RemoveComment(n); err != nil {
+				break
+			}
+			removed++
+			continue
+		}
+		n++
 	}
+	return removed
+}
+
+// addErrorComments prepends # ft error: comments to the top of a file's
+// content, replacing any written by an earlier sync. Line numbers in the
+// comments refer to the file without them. Returns the new content and how
+// far the rest of the file moved down.
+func addErrorComments(data []byte, errors []parser.ParseError) ([]byte, int, error) {
+	cst := parser.ParseCST(data)
+	removed := stripErrorComments(cst)
+	for i := len(errors) - 1; i >= 0; i-- {
+		pe := errors[i]
+		if err := cst.AddComment(1, fmt.Sprintf("ft error: %s (line %d)", pe.Message, pe.Line-removed)); err != nil {
+			return nil, 0, err
+		}
+	}
+	return cst.Bytes(), len(errors) - removed, nil
+}
 
-	content := strings.Join(errorLines, "\n") + "\n" + string(data)
+// removeErrorComments removes the # ft error: comments an earlier sync
+// wrote into a file's content, returning it unchanged if there are none.
+func removeErrorComments(data []byte) []byte {
+	cst := parser.ParseCST(data)
+	if stripErrorComments(cst) == 0 {
+		return data
+	}
+	return cst.Bytes()
+}
 
+// writeFileAtomic replaces path with data via a temporary file and rename.
+func writeFileAtomic(path string, data []byte) error {
 	tmpPath := path + ".tmp"
-	if err := os.WriteFile(tmpPath, []byte(content), 0o644); err != nil {
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
 		return err
 	}
 	return os.Rename(tmpPath, path)
@@ -521,6 +1390,9 @@ type testLink struct {
 	lineNumber int
 }
 
+// testLinkIndex maps scenario IDs to the tests tagged with them.
+type testLinkIndex map[int64][]testLink
+
 func scanTestLinksInFile(path string, src []byte) []testLink {
 	fset := token.NewFileSet()
 	var s scanner.Scanner
@@ -603,7 +1475,7 @@ func scanTestLinksInFile(path string, src []byte) []testLink {
 //     original transition time. Rows whose scenario id has no matching row
 //     (removed from its file before the DB was lost) are skipped rather
 //     than reconstructed — see design/STATUSES_FILE.md.
-func reconcileStatusesFile(store *db.Store) error {
+func reconcileStatusesFile(store *db.Store, plan *syncPlan) error {
 	dbCount, err := store.CountStatuses()
 	if err != nil {
 		return err
@@ -630,36 +1502,106 @@ func reconcileStatusesFile(store *db.Store) error {
 	if err != nil {
 		return err
 	}
-	return db.WriteStatusesFile(currentRows)
+	fileRows, err := db.ReadStatusesFile()
+	if err != nil {
+		return err
+	}
+	if !sameStatusRows(fileRows, currentRows) {
+		plan.writes = append(plan.writes, db.StatusesPath())
+	}
+	// The rows are read again for the rewrite: a status `ft status` records
+	// between this pass's commit and the rewrite must not be lost
+	return store.AfterCommit(store.WriteCurrentStatusesFile)
 }
 
-func syncTestLinks(store *db.Store) error {
-	var links []testLink
+// sameStatusRows reports whether a and b hold the same rows in any order.
+func sameStatusRows(a, b []db.StatusRow) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byID := func(rows []db.StatusRow) []db.StatusRow {
+		sorted := slices.Clone(rows)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].ScenarioID < sorted[j].ScenarioID })
+		return sorted
+	}
+	return slices.Equal(byID(a), byID(b))
+}
 
-	filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
+// scanTestLinks finds the @ft tags above Go test functions in every
+// _test.go file outside fts/. A file whose size and modification time match
+// its cached scan isn't read again, unless full, and neither is a cached
+// file missing from changed, if changed isn't nil.
+func scanTestLinks(store *db.Store, full bool, changed map[string]bool) ([]testLink, error) {
+	cache, err := store.TestFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk first, then read and scan the files the cache can't answer for
+	// in parallel; links are assembled in walk order either way
+	type testFile struct {
+		path   string
+		info   fs.FileInfo
+		cached bool
+	}
+	var files []testFile
+	err = walkTestFiles(func(path string, info fs.FileInfo) {
+		c, ok := cache[path]
+		cached := ok && !full && c.ModTime != 0 && c.Size == info.Size() && c.ModTime == info.ModTime().UnixNano()
+		if ok && !full && changed != nil && !changed[filepath.ToSlash(path)] {
+			cached = true
 		}
-		if d.IsDir() {
-			base := filepath.Base(path)
-			if base == ".git" || base == "fts" {
-				return filepath.SkipDir
-			}
-			return nil
+		files = append(files, testFile{path: path, info: info, cached: cached})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type scan struct {
+		links []testLink
+		err   error
+	}
+	scans := parallelMap(files, func(f testFile) scan {
+		if f.cached {
+			return scan{}
 		}
-		if !strings.HasSuffix(path, "_test.go") {
-			return nil
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return scan{err: err}
 		}
+		return scan{links: scanTestLinksInFile(f.path, data)}
+	})
 
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil
+	var links []testLink
+	seen := make(map[string]bool)
+	for i, f := range files {
+		if f.cached {
+			seen[f.path] = true
+			links = append(links, decodeTestTags(f.path, cache[f.path].Tags)...)
+			continue
+		}
+		if scans[i].err != nil {
+			continue
 		}
+		seen[f.path] = true
+		links = append(links, scans[i].links...)
+		tf := db.TestFile{Size: f.info.Size(), ModTime: stampTime(f.info), Tags: encodeTestTags(scans[i].links)}
+		if err := store.SaveTestFile(f.path, tf); err != nil {
+			return nil, err
+		}
+	}
 
-		links = append(links, scanTestLinksInFile(path, data)...)
-		return nil
-	})
+	for path := range cache {
+		if !seen[path] {
+			if err := store.DeleteTestFile(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return links, nil
+}
 
+func syncTestLinks(store *db.Store, plan *syncPlan, links []testLink) error {
 	// Load all valid scenario IDs in one query
 	validIDs, err := store.AllScenarioIDs()
 	if err != nil {
@@ -677,6 +1619,11 @@ func syncTestLinks(store *db.Store) error {
 		}
 	}
 
-	store.ReplaceTestLinks(records)
-	return nil
+	prev, err := store.AllTestLinks()
+	if err != nil {
+		return err
+	}
+	plan.diffTestLinks(prev, records)
+
+	return store.ReplaceTestLinks(records)
 }
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -54,7 +57,7 @@ func setupBenchProject(b *testing.B, fileCount, scenariosPerFile, testFileCount
 
 	// Initial sync to assign tags
 	buf.Reset()
-	require.NoError(b, RunSync(&buf))
+	require.NoError(b, RunSync(&buf, SyncOptions{}))
 
 	// Generate test files linking to scenario IDs
 	if testFileCount > 0 {
@@ -74,8 +77,21 @@ func setupBenchProject(b *testing.B, fileCount, scenariosPerFile, testFileCount
 
 		// Sync again to register test links
 		buf.Reset()
-		require.NoError(b, RunSync(&buf))
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
 	}
+
+	// Age every file past sync's racy window and sync once more, so the
+	// benchmarks see stamps an incremental sync can trust, as they would
+	// be on a project at rest
+	old := time.Now().Add(-time.Hour)
+	for _, dir := range []string{"fts", "pkg"} {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			require.NoError(b, os.Chtimes(filepath.Join(dir, e.Name()), old, old))
+		}
+	}
+	buf.Reset()
+	require.NoError(b, RunSync(&buf, SyncOptions{}))
 }
 
 // BenchmarkSync_Incremental_Small: 5 files, 10 scenarios each, no changes
@@ -84,7 +100,7 @@ func BenchmarkSync_Incremental_Small(b *testing.B) {
 	var buf bytes.Buffer
 	for b.Loop() {
 		buf.Reset()
-		require.NoError(b, RunSync(&buf))
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
 	}
 }
 
@@ -95,7 +111,7 @@ func BenchmarkSync_Incremental_Medium(b *testing.B) {
 
 	for b.Loop() {
 		buf.Reset()
-		require.NoError(b, RunSync(&buf))
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
 	}
 }
 
@@ -105,7 +121,38 @@ func BenchmarkSync_Incremental_Large(b *testing.B) {
 	var buf bytes.Buffer
 	for b.Loop() {
 		buf.Reset()
-		require.NoError(b, RunSync(&buf))
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
+	}
+}
+
+// BenchmarkSync_Full_Large: 50 files, 50 scenarios each, no changes, with
+// --full; compare with BenchmarkSync_Incremental_Large
+func BenchmarkSync_Full_Large(b *testing.B) {
+	setupBenchProject(b, 50, 50, 0)
+	var buf bytes.Buffer
+	for b.Loop() {
+		buf.Reset()
+		require.NoError(b, RunSync(&buf, SyncOptions{Full: true}))
+	}
+}
+
+// BenchmarkSync_OneFileChanged_Large: 50 files, 50 scenarios each, one
+// file edited before every sync, as on save in an editor
+func BenchmarkSync_OneFileChanged_Large(b *testing.B) {
+	setupBenchProject(b, 50, 50, 0)
+	path := "fts/feature_0.ft"
+	data, err := os.ReadFile(path)
+	require.NoError(b, err)
+	versions := []string{string(data), strings.Replace(string(data), "Given precondition 1\n", "Given precondition one\n", 1)}
+	var buf bytes.Buffer
+	i := 0
+	for b.Loop() {
+		b.StopTimer()
+		i++
+		require.NoError(b, os.WriteFile(path, []byte(versions[i%2]), 0o644))
+		buf.Reset()
+		b.StartTimer()
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
 	}
 }
 
@@ -115,7 +162,7 @@ func BenchmarkSync_WithTestLinks_Small(b *testing.B) {
 	var buf bytes.Buffer
 	for b.Loop() {
 		buf.Reset()
-		require.NoError(b, RunSync(&buf))
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
 	}
 }
 
@@ -125,7 +172,18 @@ func BenchmarkSync_WithTestLinks_Large(b *testing.B) {
 	var buf bytes.Buffer
 	for b.Loop() {
 		buf.Reset()
-		require.NoError(b, RunSync(&buf))
+		require.NoError(b, RunSync(&buf, SyncOptions{}))
+	}
+}
+
+// BenchmarkSync_WithTestLinks_Full_Large: 20 ft files + 50 test files,
+// with --full; compare with BenchmarkSync_WithTestLinks_Large
+func BenchmarkSync_WithTestLinks_Full_Large(b *testing.B) {
+	setupBenchProject(b, 20, 20, 50)
+	var buf bytes.Buffer
+	for b.Loop() {
+		buf.Reset()
+		require.NoError(b, RunSync(&buf, SyncOptions{Full: true}))
 	}
 }
 
@@ -146,7 +204,7 @@ func BenchmarkSync_FirstSync_Small(b *testing.B) {
 
 		buf.Reset()
 		b.StartTimer()
-		RunSync(&buf)
+		RunSync(&buf, SyncOptions{})
 		b.StopTimer()
 		os.Chdir(orig)
 		b.StartTimer()
@@ -170,7 +228,7 @@ func BenchmarkSync_FirstSync_Large(b *testing.B) {
 
 		buf.Reset()
 		b.StartTimer()
-		RunSync(&buf)
+		RunSync(&buf, SyncOptions{})
 		b.StopTimer()
 		os.Chdir(orig)
 		b.StartTimer()
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/parser"
+)
+
+// fileHash returns the hex SHA-256 of a file's content.
+func fileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// racyWindow is how recently a file can have been modified for its
+// modification time to be trusted. A file written again within the same
+// clock tick, at the same size, would otherwise look unchanged.
+const racyWindow = 2 * time.Second
+
+// stampTime returns a file's modification time to compare later syncs
+// against, or 0 if it's too recent to trust, so they compare content.
+func stampTime(info fs.FileInfo) int64 {
+	if info == nil || time.Since(info.ModTime()) < racyWindow {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// recordFileStamp stores the hash and size of a file's content as this sync
+// leaves it. A file the sync rewrites gets its modification time once it's
+// next seen, so it's recorded as 0 and the next sync compares hashes. A
+// file with restoredComment gets no stamp, so every sync reads it again to
+// see whether the comment can go.
+func recordFileStamp(store *db.Store, fileID int64, info fs.FileInfo, original, updated []byte) error {
+	if hasRestoredComment(updated) {
+		return store.ClearFileStamp(fileID)
+	}
+	var modTime int64
+	if bytes.Equal(original, updated) {
+		modTime = stampTime(info)
+	}
+	return store.UpdateFileStamp(fileID, fileHash(updated), int64(len(updated)), modTime)
+}
+
+// reloadTaggedFiles parses the unchanged files holding a scenario that a
+// changed file carries the tag of: the changed file has a copy of it, or
+// it was moved there, and reconciling either needs both files. Each
+// reloaded file is taken out of unchanged.
+func reloadTaggedFiles(store *db.Store, parsed map[string]*parser.ParsedFile, changed []string, unchanged map[string]bool, parse func(string, []byte)) error {
+	for _, path := range changed {
+		for _, ps := range append(parsed[path].Scenarios, parsed[path].Skipped...) {
+			id, err := strconv.ParseInt(ps.FtTag, 10, 64)
+			if err != nil {
+				continue
+			}
+			d, err := store.ScenarioDetail(id)
+			if err != nil || !unchanged[d.FilePath] {
+				continue
+			}
+			content, err := os.ReadFile(d.FilePath)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", d.FilePath, err)
+			}
+			delete(unchanged, d.FilePath)
+			parse(d.FilePath, content)
+		}
+	}
+	return nil
+}
+
+// encodeTestTags records a test file's links for the test file cache as
+// space-separated line:id pairs.
+func encodeTestTags(links []testLink) string {
+	pairs := make([]string, len(links))
+	for i, l := range links {
+		pairs[i] = fmt.Sprintf("%d:%d", l.lineNumber, l.scenarioID)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// decodeTestTags turns the cached tags of the test file at path back into
+// links.
+func decodeTestTags(path, tags string) []testLink {
+	var links []testLink
+	for _, pair := range strings.Fields(tags) {
+		lineStr, idStr, _ := strings.Cut(pair, ":")
+		line, err := strconv.Atoi(lineStr)
+		if err != nil {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		links = append(links, testLink{scenarioID: id, filePath: path, lineNumber: line})
+	}
+	return links
+}
+
+// ftFileRead is what reading one .ft file found, for syncFiles to apply.
+type ftFileRead struct {
+	info      fs.FileInfo
+	unchanged bool // the same as at the last sync
+	touched   bool // unchanged, but with a new modification time
+	content   []byte
+	parsed    *parser.ParsedFile
+	err       error
+}
+
+// readFtFile stats the .ft file at path and, unless its stamp shows it
+// unchanged, reads, hashes and parses it. It only reads the file system, so
+// it's safe to call from several goroutines. trusted says whether st can be
+// used to skip the file at all.
+func readFtFile(path string, st db.FileStamp, trusted bool) ftFileRead {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ftFileRead{err: err}
+	}
+	if trusted && st.Size == info.Size() && st.ModTime == info.ModTime().UnixNano() {
+		return ftFileRead{info: info, unchanged: true}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ftFileRead{err: err}
+	}
+	if trusted && st.Hash == fileHash(content) {
+		return ftFileRead{info: info, unchanged: true, touched: true}
+	}
+	doc, parseErrors := parser.Parse(path, content)
+	pf := parser.Transform(doc, path, content, parseErrors)
+	return ftFileRead{info: info, content: content, parsed: pf}
+}
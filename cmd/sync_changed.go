@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// changedSince lists the files that differ from ref in the working tree, as
+// git reports them relative to the project root, along with untracked files
+// git doesn't ignore. Deleted files are listed too, though sync finds those
+// by itself.
+func changedSince(ref string) ([]string, error) {
+	diff, err := git("diff", "--name-only", "--relative", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return append(splitPaths(diff), splitPaths(untracked)...), nil
+}
+
+// git runs git with args in the project root and returns its output.
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// git follows most errors with its usage, so keep the first line
+		msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return out, nil
+}
+
+// readChangedFiles reads a list of changed files, one path per line relative
+// to the project root, like the output of `git diff --name-only --relative`,
+// from r, or from the file at path unless path is "-".
+func readChangedFiles(path string, r io.Reader) ([]string, error) {
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("reading changed files: %w", err)
+	}
+	return splitPaths(data), nil
+}
+
+// splitPaths splits a newline-separated list of paths, skipping blank lines.
+func splitPaths(data []byte) []string {
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// changedSet indexes paths by their cleaned, slash-separated form, the form
+// sync compares file paths in.
+func changedSet(paths []string) map[string]bool {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[filepath.ToSlash(filepath.Clean(p))] = true
+	}
+	return set
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/ui"
+)
+
+// errDryRun rolls back the sync transaction of a --dry-run or --check pass.
+var errDryRun = errors.New("dry run")
+
+// errOutOfSync is returned by --check when a sync would change something.
+var errOutOfSync = errors.New("fts/ is out of sync: run `ft sync` and commit the result")
+
+// syncPlan records what a sync pass changes beyond the file and scenario
+// lines it prints, so --dry-run can report it and --check can tell whether
+// the files are out of sync.
+type syncPlan struct {
+	changes      int          // ren, mod and del files, and scenarios moved into new files
+	tags         []plannedTag // @ft tags written into .ft files
+	writes       []string     // files whose content changes
+	statuses     []db.StatusRow
+	linksAdded   []db.TestLinkRecord
+	linksRemoved []db.TestLinkRecord
+}
+
+// plannedTag is an @ft:<id> tag written above the Scenario: on line.
+type plannedTag struct {
+	path string
+	line int
+	id   int64
+}
+
+func (p *syncPlan) addTags(path string, insertions []tagInsertion) {
+	for _, ins := range insertions {
+		p.tags = append(p.tags, plannedTag{path: path, line: ins.line, id: ins.id})
+	}
+}
+
+// outOfSync reports whether the pass changes a committed file or the
+// scenarios it tracks. Test links and scenarios registered from tags
+// already in their files don't count, so a pass over a freshly rebuilt
+// database, e.g. in CI, is in sync when nothing else changed.
+func (p *syncPlan) outOfSync() bool {
+	return p.changes > 0 || len(p.tags) > 0 || len(p.writes) > 0 || len(p.statuses) > 0
+}
+
+// diffTestLinks records the links in next that aren't in prev as added and
+// those in prev that aren't in next as removed.
+func (p *syncPlan) diffTestLinks(prev, next []db.TestLinkRecord) {
+	inPrev := make(map[db.TestLinkRecord]bool, len(prev))
+	for _, l := range prev {
+		inPrev[l] = true
+	}
+	inNext := make(map[db.TestLinkRecord]bool, len(next))
+	for _, l := range next {
+		inNext[l] = true
+		if !inPrev[l] {
+			p.linksAdded = append(p.linksAdded, l)
+		}
+	}
+	for _, l := range prev {
+		if !inNext[l] {
+			p.linksRemoved = append(p.linksRemoved, l)
+		}
+	}
+	sortTestLinks(p.linksAdded)
+	sortTestLinks(p.linksRemoved)
+}
+
+func sortTestLinks(links []db.TestLinkRecord) {
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.LineNumber != b.LineNumber {
+			return a.LineNumber < b.LineNumber
+		}
+		return a.ScenarioID < b.ScenarioID
+	})
+}
+
+// sortedTags returns the tags in the plan by file and line.
+func (p *syncPlan) sortedTags() []plannedTag {
+	tags := slices.Clone(p.tags)
+	sort.SliceStable(tags, func(i, j int) bool {
+		if tags[i].path != tags[j].path {
+			return tags[i].path < tags[j].path
+		}
+		return tags[i].line < tags[j].line
+	})
+	return tags
+}
+
+// print lists the tags, statuses, test links and file writes in the plan.
+func (p *syncPlan) print(w io.Writer) {
+	if len(p.tags) > 0 {
+		ui.PlanHeader(w, "tags to write")
+		for _, t := range p.sortedTags() {
+			ui.PlannedTagLine(w, t.path, t.line, t.id)
+		}
+	}
+	if len(p.statuses) > 0 {
+		ui.PlanHeader(w, "statuses to add")
+		for _, s := range p.statuses {
+			ui.PlannedStatusLine(w, s.ScenarioID, s.Status)
+		}
+	}
+	if len(p.linksAdded) > 0 || len(p.linksRemoved) > 0 {
+		ui.PlanHeader(w, "test links")
+		for _, l := range p.linksAdded {
+			ui.PlannedLinkLine(w, true, l.ScenarioID, l.FilePath, l.LineNumber)
+		}
+		for _, l := range p.linksRemoved {
+			ui.PlannedLinkLine(w, false, l.ScenarioID, l.FilePath, l.LineNumber)
+		}
+	}
+	if len(p.writes) > 0 {
+		ui.PlanHeader(w, "files to write")
+		for _, path := range p.writes {
+			ui.PlannedWriteLine(w, path)
+		}
+	}
+}
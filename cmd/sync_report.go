@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/ui"
+)
+
+// syncReport receives what a sync pass does, as it does it: printed as
+// marker lines for people, or as JSON events with --json.
+type syncReport interface {
+	// file reports a file's action: "new", "unchanged", "modified",
+	// "renamed" (from the previous path), "deleted" or "restored".
+	file(action, path, from string)
+	// scenario reports what happened to a scenario of the file at path.
+	scenario(path string, a scenarioAction)
+	diagnostic(d db.Diagnostic)
+	// conflict reports a removed scenario that the tests still link to.
+	conflict(a scenarioAction, tests []string)
+	// hints follows the conflicts and unsynced duplicate tags with how to
+	// resolve them.
+	hints(conflicts, duplicates bool)
+	// summary ends the pass with what plan records and the counts.
+	summary(plan *syncPlan, fileCount, scenarioCount int, dryRun bool)
+	// err returns the first error writing the report, if any.
+	err() error
+}
+
+// newSyncReport returns the report for opts, writing to w.
+func newSyncReport(w io.Writer, opts SyncOptions) syncReport {
+	if opts.JSON {
+		return &jsonReport{enc: json.NewEncoder(w)}
+	}
+	return textReport{w: w}
+}
+
+// textReport prints the marker lines of FT_SYNC.md.
+type textReport struct {
+	w io.Writer
+}
+
+func (r textReport) file(action, path, from string) {
+	switch action {
+	case "new":
+		ui.NewLine(r.w, path)
+	case "unchanged":
+		ui.TrkLine(r.w, path)
+	case "modified":
+		ui.ModLine(r.w, path)
+	case "renamed":
+		ui.RenLine(r.w, from, path)
+	case "deleted":
+		ui.DelLine(r.w, path)
+	case "restored":
+		ui.RstLine(r.w, path)
+	}
+}
+
+func (r textReport) scenario(path string, a scenarioAction) {
+	switch a.kind {
+	case "new", "restored":
+		ui.ScenarioLine(r.w, a.id, a.name)
+	case "modified":
+		ui.ModifiedScenarioLine(r.w, a.id, a.name)
+	case "matched":
+		ui.MatchedScenarioLine(r.w, a.id, a.name, a.prevName, a.score)
+	case "moved":
+		ui.MovedScenarioLine(r.w, a.id, a.name, a.from)
+	case "removed":
+		ui.RemovedScenarioLine(r.w, a.id, a.name)
+	case "rehydrated":
+		ui.RehydratedScenarioLine(r.w, a.id, a.name)
+	case "retagged":
+		ui.RetaggedScenarioLine(r.w, a.id, a.name, a.was)
+	}
+}
+
+func (r textReport) diagnostic(d db.Diagnostic) {
+	ui.ErrLine(r.w, fmt.Sprintf("%s:%d:%d", d.FilePath, d.Line, d.Column), d.Message+lineRange(d.Line, d.EndLine))
+}
+
+func (r textReport) conflict(a scenarioAction, tests []string) {
+	ui.ConflictLine(r.w, a.id, a.name, tests)
+}
+
+func (r textReport) hints(conflicts, duplicates bool) {
+	if conflicts {
+		ui.ConflictHint(r.w)
+	}
+	if duplicates {
+		ui.DuplicateHint(r.w)
+	}
+}
+
+// summary prints the plan only for a dry run: a real sync's tags and
+// statuses are in the files it wrote.
+func (r textReport) summary(plan *syncPlan, fileCount, scenarioCount int, dryRun bool) {
+	if !dryRun {
+		ui.SummaryLine(r.w, fileCount, scenarioCount)
+		return
+	}
+	plan.print(r.w)
+	ui.DryRunSummaryLine(r.w, fileCount, scenarioCount)
+}
+
+// err is always nil: like the rest of ft's text output, the lines are
+// printed without checking.
+func (r textReport) err() error { return nil }
+
+// jsonReport writes one JSON object per line, each with an "event" naming
+// its kind. See FT_SYNC.md for the events and their fields.
+type jsonReport struct {
+	enc      *json.Encoder
+	firstErr error // the first error encoding an event; no event is written after it
+}
+
+type fileEvent struct {
+	Event  string `json:"event"`
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	From   string `json:"from,omitempty"`
+}
+
+type scenarioEvent struct {
+	Event   string  `json:"event"`
+	Action  string  `json:"action"`
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Path    string  `json:"path"`
+	From    string  `json:"from,omitempty"`
+	Was     int64   `json:"was,omitempty"`
+	Matched string  `json:"matched,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+}
+
+type diagnosticEvent struct {
+	Event    string `json:"event"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type conflictEvent struct {
+	Event string   `json:"event"`
+	ID    int64    `json:"id"`
+	Name  string   `json:"name"`
+	Tests []string `json:"tests"`
+}
+
+type tagEvent struct {
+	Event string `json:"event"`
+	Path  string `json:"path"`
+	Line  int    `json:"line"`
+	ID    int64  `json:"id"`
+}
+
+type statusEvent struct {
+	Event  string `json:"event"`
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+type testLinkEvent struct {
+	Event  string `json:"event"`
+	Action string `json:"action"`
+	ID     int64  `json:"id"`
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+}
+
+type writeEvent struct {
+	Event string `json:"event"`
+	Path  string `json:"path"`
+}
+
+type summaryEvent struct {
+	Event     string `json:"event"`
+	Files     int    `json:"files"`
+	Scenarios int    `json:"scenarios"`
+	DryRun    bool   `json:"dry_run"`
+}
+
+// encode writes one event, keeping the first error for err.
+func (r *jsonReport) encode(event any) {
+	if r.firstErr != nil {
+		return
+	}
+	if err := r.enc.Encode(event); err != nil {
+		r.firstErr = fmt.Errorf("writing JSON output: %w", err)
+	}
+}
+
+func (r *jsonReport) err() error { return r.firstErr }
+
+func (r *jsonReport) file(action, path, from string) {
+	r.encode(fileEvent{Event: "file", Action: action, Path: path, From: from})
+}
+
+func (r *jsonReport) scenario(path string, a scenarioAction) {
+	r.encode(scenarioEvent{
+		Event:   "scenario",
+		Action:  a.kind,
+		ID:      a.id,
+		Name:    a.name,
+		Path:    path,
+		From:    a.from,
+		Was:     a.was,
+		Matched: a.prevName,
+		Score:   a.score,
+	})
+}
+
+func (r *jsonReport) diagnostic(d db.Diagnostic) {
+	r.encode(diagnosticEvent{
+		Event:    "diagnostic",
+		Path:     d.FilePath,
+		Line:     d.Line,
+		EndLine:  d.EndLine,
+		Column:   d.Column,
+		Severity: d.Severity,
+		Message:  d.Message,
+	})
+}
+
+func (r *jsonReport) conflict(a scenarioAction, tests []string) {
+	r.encode(conflictEvent{Event: "conflict", ID: a.id, Name: a.name, Tests: tests})
+}
+
+// hints are for people reading the text output; the conflict events and
+// duplicate tag diagnostics already say what's wrong.
+func (r *jsonReport) hints(conflicts, duplicates bool) {}
+
+// summary writes the plan as events for a real sync as well as a dry run,
+// so scripts learn the tags and statuses written without reading the files.
+func (r *jsonReport) summary(plan *syncPlan, fileCount, scenarioCount int, dryRun bool) {
+	for _, t := range plan.sortedTags() {
+		r.encode(tagEvent{Event: "tag", Path: t.path, Line: t.line, ID: t.id})
+	}
+	for _, s := range plan.statuses {
+		r.encode(statusEvent{Event: "status", ID: s.ScenarioID, Status: s.Status})
+	}
+	for _, l := range plan.linksAdded {
+		r.encode(testLinkEvent{Event: "test_link", Action: "added", ID: l.ScenarioID, Path: l.FilePath, Line: l.LineNumber})
+	}
+	for _, l := range plan.linksRemoved {
+		r.encode(testLinkEvent{Event: "test_link", Action: "removed", ID: l.ScenarioID, Path: l.FilePath, Line: l.LineNumber})
+	}
+	for _, path := range plan.writes {
+		r.encode(writeEvent{Event: "write", Path: path})
+	}
+	r.encode(summaryEvent{Event: "summary", Files: fileCount, Scenarios: scenarioCount, DryRun: dryRun})
+}
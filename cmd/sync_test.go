@@ -2,21 +2,33 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/chriserin/ft/internal/db"
 	"github.com/chriserin/ft/internal/db/dbtest"
 )
 
 func runSync(t *testing.T) string {
+	t.Helper()
+	return runSyncWith(t, SyncOptions{})
+}
+
+func runSyncWith(t *testing.T, opts SyncOptions) string {
 	t.Helper()
 	var buf bytes.Buffer
-	require.NoError(t, RunSync(&buf))
+	require.NoError(t, RunSync(&buf, opts))
 	return buf.String()
 }
 
@@ -141,7 +153,7 @@ func TestSync_WithoutInit(t *testing.T) {
 	inTempDir(t)
 
 	var buf bytes.Buffer
-	err := RunSync(&buf)
+	err := RunSync(&buf, SyncOptions{})
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "run `ft init` first")
@@ -154,7 +166,7 @@ func TestSync_FilesTableMigration(t *testing.T) {
 
 	fx := dbtest.Open(t, "fts/ft.db")
 	assert.True(t, fx.TableExists("files"))
-	assert.Equal(t, 6, fx.SchemaVersion())
+	assert.Equal(t, 15, fx.SchemaVersion())
 }
 
 // Phase 3 tests
@@ -314,36 +326,6 @@ func TestSync_ScenariosFromMultipleFiles(t *testing.T) {
 	assert.Equal(t, 1, fx.CountScenariosByName("User completes purchase"))
 }
 
-// @ft:27
-func TestSync_RejectScenarioOutline(t *testing.T) {
-	inTempDir(t)
-	runInit(t)
-	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
-  Scenario Outline: User logs in
-    Given a user
-`), 0o644))
-
-	out := runSync(t)
-
-	assert.Contains(t, out, "err  fts/login.ft")
-	assert.Contains(t, out, "Scenario Outline is not supported")
-}
-
-// @ft:28
-func TestSync_RejectRule(t *testing.T) {
-	inTempDir(t)
-	runInit(t)
-	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
-  Rule: Business rule
-    Scenario: Test
-`), 0o644))
-
-	out := runSync(t)
-
-	assert.Contains(t, out, "err  fts/login.ft")
-	assert.Contains(t, out, "Rule is not supported")
-}
-
 // @ft:29
 func TestSync_RejectExamples(t *testing.T) {
 	inTempDir(t)
@@ -356,7 +338,7 @@ func TestSync_RejectExamples(t *testing.T) {
 	out := runSync(t)
 
 	assert.Contains(t, out, "err  fts/login.ft")
-	assert.Contains(t, out, "Examples is not supported")
+	assert.Contains(t, out, "Examples must belong to a Scenario Outline")
 }
 
 // @ft:30
@@ -367,11 +349,11 @@ func TestSync_ErrorCommentWrittenToFile(t *testing.T) {
   Background:
     Given setup
 
-  Scenario Outline: User logs in
-    Given a user
+  Examples: Orphaned
+    | a |
 `), 0o644))
 
-	runSync(t)
+	runSyncWith(t, SyncOptions{WriteErrors: true})
 
 	data, err := os.ReadFile("fts/login.ft")
 	require.NoError(t, err)
@@ -383,12 +365,12 @@ func TestSync_ErrorCommentWrittenToFile(t *testing.T) {
 }
 
 // @ft:31
-func TestSync_FileWithErrorIsSkipped(t *testing.T) {
+func TestSync_ValidScenariosInFileWithErrorAreSynced(t *testing.T) {
 	inTempDir(t)
 	runInit(t)
 	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
-  Scenario Outline: Parameterized
-    Given a user
+  Examples: Orphaned
+    | a |
 
   Scenario: User logs in
     Given a user
@@ -397,7 +379,8 @@ func TestSync_FileWithErrorIsSkipped(t *testing.T) {
 	runSync(t)
 
 	fx := dbtest.Open(t, "fts/ft.db")
-	assert.Equal(t, 0, fx.CountScenarios())
+	assert.Equal(t, 1, fx.CountScenarios())
+	assert.Equal(t, 1, fx.CountScenariosByName("User logs in"))
 }
 
 // @ft:32
@@ -519,7 +502,7 @@ func TestSync_NewFileWithClaimedTagStripsAndAssignsNewID(t *testing.T) {
     Given a user
 `), 0o644))
 
-	out := runSync(t)
+	out := runSyncWith(t, SyncOptions{FixDuplicates: true})
 
 	fx := dbtest.Open(t, "fts/ft.db")
 
@@ -584,6 +567,200 @@ func TestSync_NewFileWithUntaggedScenarioGetsFreshID(t *testing.T) {
 	assert.Contains(t, string(data), fmt.Sprintf("@ft:%d", id))
 }
 
+// @ft:238
+func TestSync_RegistersScenarioOutline(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Examples:
+      | role  |
+      | admin |
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.NotContains(t, out, "err")
+	assert.Contains(t, out, "@ft:1 User logs in as <role>")
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	lines := strings.Split(string(data), "\n")
+	require.Len(t, lines, 9)
+	assert.Equal(t, "  @ft:1", lines[1])
+	assert.Equal(t, "  Scenario Outline: User logs in as <role>", lines[2])
+}
+
+// @ft:239
+func TestSync_ScenarioOutlineContentIncludesExamples(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Examples:
+      | role  |
+      | admin |
+
+  Scenario: User logs out
+    Given a user
+`), 0o644))
+
+	runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	content := fx.ScenarioContent(1).String
+	assert.Contains(t, content, "Examples:")
+	assert.Contains(t, content, "| admin |")
+	assert.NotContains(t, content, "User logs out")
+}
+
+// @ft:240
+func TestSync_ExamplesRowChangeMarksOutlineModified(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Examples:
+      | role  |
+      | admin |
+`), 0o644))
+	runSync(t)
+
+	setupFx := dbtest.Open(t, "fts/ft.db")
+	setupFx.InsertStatus(1, "accepted")
+	setupFx.Close()
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	updated := strings.Replace(string(data), "| admin |", "| admin |\n      | guest |", 1)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(updated), 0o644))
+
+	out := runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "modified", fx.LatestStatusByID(1))
+	assert.Contains(t, out, "mod  fts/login.ft")
+}
+
+// @ft:241
+func TestSync_ExamplesTableRealignmentDoesNotMarkModified(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Examples:
+      | role | page |
+      | admin | settings |
+`), 0o644))
+	runSync(t)
+
+	setupFx := dbtest.Open(t, "fts/ft.db")
+	setupFx.InsertStatus(1, "accepted")
+	setupFx.Close()
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	updated := strings.Replace(string(data), "| role | page |", "| role  | page     |", 1)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(updated), 0o644))
+
+	out := runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+	assert.Contains(t, out, "trk  fts/login.ft")
+}
+
+// @ft:244
+func TestSync_RegistersScenariosInsideRules(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/refunds.ft", []byte(`Feature: Refunds
+  Rule: Refunds need a receipt
+    Scenario: Refund with receipt
+      Given a receipt
+
+  Rule: Refunds expire
+    Scenario: Refund after 30 days
+      Given an old purchase
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.NotContains(t, out, "err")
+	assert.Contains(t, out, "@ft:1 Refund with receipt")
+	assert.Contains(t, out, "@ft:2 Refund after 30 days")
+
+	data, err := os.ReadFile("fts/refunds.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "    @ft:1\n    Scenario: Refund with receipt")
+	assert.Contains(t, string(data), "    @ft:2\n    Scenario: Refund after 30 days")
+}
+
+// @ft:245
+func TestSync_StoresScenarioRule(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/refunds.ft", []byte(`Feature: Refunds
+  Scenario: Refund page loads
+    Given the refund page
+
+  Rule: Refunds need a receipt
+    Scenario: Refund with receipt
+      Given a receipt
+`), 0o644))
+
+	runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.False(t, fx.ScenarioRule(1).Valid)
+	assert.Equal(t, "Refunds need a receipt", fx.ScenarioRule(2).String)
+	assert.NotContains(t, fx.ScenarioContent(1).String, "Rule:")
+}
+
+// @ft:246
+func TestSync_MovingScenarioBetweenRulesUpdatesRuleWithoutModifiedStatus(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/refunds.ft", []byte(`Feature: Refunds
+  Rule: Refunds need a receipt
+    @ft:1
+    Scenario: Refund with receipt
+      Given a receipt
+
+  Rule: Refunds expire
+`), 0o644))
+	runSync(t)
+
+	setupFx := dbtest.Open(t, "fts/ft.db")
+	setupFx.InsertStatus(1, "accepted")
+	setupFx.Close()
+
+	require.NoError(t, os.WriteFile("fts/refunds.ft", []byte(`Feature: Refunds
+  Rule: Refunds need a receipt
+
+  Rule: Refunds expire
+    @ft:1
+    Scenario: Refund with receipt
+      Given a receipt
+`), 0o644))
+
+	out := runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "Refunds expire", fx.ScenarioRule(1).String)
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+	assert.Contains(t, out, "mod  fts/refunds.ft")
+	assert.Contains(t, out, "~")
+}
+
 // @ft:37
 func TestSync_ScenariosTableMigration(t *testing.T) {
 	inTempDir(t)
@@ -591,7 +768,7 @@ func TestSync_ScenariosTableMigration(t *testing.T) {
 
 	fx := dbtest.Open(t, "fts/ft.db")
 	assert.True(t, fx.TableExists("scenarios"))
-	assert.Equal(t, 6, fx.SchemaVersion())
+	assert.Equal(t, 15, fx.SchemaVersion())
 }
 
 // Phase 7 tests
@@ -1986,3 +2163,1729 @@ func TestSync_DoesNotReplayFileIntoNonEmptyDatabase(t *testing.T) {
 	fx2 := dbtest.Open(t, "fts/ft.db")
 	assert.Equal(t, before, fx2.CountStatuses(1))
 }
+
+// @ft:249
+func TestSync_RegistersLocalizedScenarios(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/anmeldung.ft", []byte(`# language: de
+Funktionalität: Anmeldung
+  Grundlage:
+    Angenommen ein registrierter Benutzer
+
+  Szenario: Erfolgreiche Anmeldung
+    Wenn er sich anmeldet
+    Dann sieht er das Dashboard
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.NotContains(t, out, "err")
+	assert.Contains(t, out, "@ft:1 Erfolgreiche Anmeldung")
+
+	data, err := os.ReadFile("fts/anmeldung.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "  @ft:1\n  Szenario: Erfolgreiche Anmeldung")
+}
+
+// @ft:251
+func TestSync_TagWritePreservesFileBytes(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	original := "Feature: Login  \r\n\r\n\t@smoke\r\n\tScenario: User logs in\r\n\t\tGiven a user\r\n\r\n\tScenario: User logs out\r\n\t\tGiven a user"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(original), 0o644))
+
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login  \r\n\r\n\t@smoke\r\n\t@ft:1\r\n\tScenario: User logs in\r\n\t\tGiven a user\r\n\r\n\t@ft:2\r\n\tScenario: User logs out\r\n\t\tGiven a user", string(data))
+}
+
+// @ft:252
+func TestSync_ErrorCommentPreservesFileBytes(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\r\n  Examples: Orphaned\r\n    | a |"), 0o644))
+
+	runSyncWith(t, SyncOptions{WriteErrors: true})
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "# ft error: Examples must belong to a Scenario Outline (line 2)\r\nFeature: Login\r\n  Examples: Orphaned\r\n    | a |", string(data))
+}
+
+// @ft:261
+func TestSync_ErrorsRecordedAsDiagnostics(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	original := "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n    Examples: Orphaned\n      | a |\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(original), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "err  fts/login.ft:5:5 — Examples must belong to a Scenario Outline")
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data), "file is untouched without --write-errors")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	require.Equal(t, 1, fx.CountDiagnostics("fts/login.ft"))
+	line, column, severity, message := fx.Diagnostic("fts/login.ft")
+	assert.Equal(t, 5, line)
+	assert.Equal(t, 5, column)
+	assert.Equal(t, "error", severity)
+	assert.Equal(t, "Examples must belong to a Scenario Outline", message)
+}
+
+// @ft:262
+func TestSync_DiagnosticsClearedWhenFixed(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Examples: Orphaned\n    | a |\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/gone.ft", []byte("Feature: Gone\n  Examples: Orphaned\n    | a |\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.Remove("fts/gone.ft"))
+	runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountDiagnostics("fts/login.ft"))
+	assert.Equal(t, 0, fx.CountDiagnostics("fts/gone.ft"))
+}
+
+// @ft:263
+func TestSync_WriteErrorsDoesNotDuplicateComments(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Examples: Orphaned\n    | a |\n"), 0o644))
+
+	runSyncWith(t, SyncOptions{WriteErrors: true})
+	runSyncWith(t, SyncOptions{WriteErrors: true})
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "# ft error: Examples must belong to a Scenario Outline (line 2)\nFeature: Login\n  Examples: Orphaned\n    | a |\n", string(data))
+}
+
+// @ft:264
+func TestSync_StaleErrorCommentsRemoved(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("# ft error: Examples must belong to a Scenario Outline (line 3)\n# a note\nFeature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "# a note\nFeature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n", string(data))
+}
+
+// @ft:267
+func TestSync_BrokenScenarioSkippedOthersTagged(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User logs in
+    Given a user
+
+  Scenario: User logs out
+    Given a user
+
+    Examples:
+      | a |
+
+  Scenario: User resets password
+    Given a user
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "+ @ft:1 User logs in")
+	assert.Contains(t, out, "+ @ft:2 User resets password")
+	assert.NotContains(t, out, "User logs out")
+	assert.Contains(t, out, "err  fts/login.ft:9:5 — Examples must belong to a Scenario Outline (lines 9-10)")
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, `Feature: Login
+  @ft:1
+  Scenario: User logs in
+    Given a user
+
+  Scenario: User logs out
+    Given a user
+
+    Examples:
+      | a |
+
+  @ft:2
+  Scenario: User resets password
+    Given a user
+`, string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 2, fx.CountScenarios())
+	assert.Equal(t, 0, fx.CountScenariosByName("User logs out"))
+	line, endLine := fx.DiagnosticRange("fts/login.ft")
+	assert.Equal(t, 9, line, "diagnostic points at the region after tags were written")
+	assert.Equal(t, 10, endLine)
+}
+
+// @ft:268
+func TestSync_BrokenTrackedScenarioKeepsState(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User logs in
+    Given a user
+
+  Scenario: User logs out
+    Given a user
+`), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "2", "accepted")
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  @ft:1
+  Scenario: User logs in
+    Given a user
+    Then they are in
+
+  @ft:2
+  Scenario: User logs out
+    Given a user
+
+    Examples:
+      | a |
+`), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "~ @ft:1 User logs in")
+	assert.NotContains(t, out, "@ft:2")
+	assert.Contains(t, out, "err  fts/login.ft:11:5")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "accepted", fx.LatestStatusByID(2))
+	assert.Equal(t, "  Scenario: User logs out\n    Given a user", fx.ScenarioContent(2).String)
+}
+
+// @ft:269
+func TestSync_FatalErrorSkipsWholeFile(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("# language: xx\nFeature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "trk  fts/login.ft")
+	assert.Contains(t, out, `err  fts/login.ft:1:1 — Unknown language "xx"`)
+	assert.NotContains(t, out, "@ft:1")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenariosByName("User logs in"))
+	assert.Equal(t, 0, fx.CountStatuses(1), "scenario is not marked removed")
+}
+
+// @ft:270
+func TestSync_DiscoversNestedFiles(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.MkdirAll("fts/billing/refunds", 0o755))
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/billing/invoices.ft", []byte("Feature: Invoices\n  Scenario: Invoice is sent\n    Given an order\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/billing/refunds/partial.ft", []byte("Feature: Refunds\n  Scenario: Partial refund\n    Given an order\n"), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "new  fts/billing/invoices.ft")
+	assert.Contains(t, out, "new  fts/billing/refunds/partial.ft")
+	assert.Contains(t, out, "synced 3 files, 3 scenarios")
+
+	data, err := os.ReadFile("fts/billing/refunds/partial.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "@ft:")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "fts/billing/invoices.ft", fx.FilePath("fts/billing/invoices.ft"))
+	assert.Equal(t, "fts/billing/refunds/partial.ft", fx.FilePath("fts/billing/refunds/partial.ft"))
+}
+
+// @ft:271
+func TestSync_FtIgnoreSkipsFilesAndDirectories(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.MkdirAll("fts/drafts", 0o755))
+	require.NoError(t, os.MkdirAll("fts/billing", 0o755))
+	require.NoError(t, os.WriteFile("fts/.ftignore", []byte("# work in progress\ndrafts/\n*.wip.ft\nbilling/*.ft\n!billing/invoices.ft\n"), 0o644))
+	scenario := []byte("Feature: F\n  Scenario: S\n    Given a step\n")
+	for _, p := range []string{"fts/login.ft", "fts/drafts/idea.ft", "fts/checkout.wip.ft", "fts/billing/refunds.ft", "fts/billing/invoices.ft"} {
+		require.NoError(t, os.WriteFile(p, scenario, 0o644))
+	}
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "new  fts/login.ft")
+	assert.Contains(t, out, "new  fts/billing/invoices.ft")
+	assert.NotContains(t, out, "drafts")
+	assert.NotContains(t, out, "checkout.wip.ft")
+	assert.NotContains(t, out, "refunds.ft")
+	assert.Contains(t, out, "synced 2 files")
+
+	data, err := os.ReadFile("fts/drafts/idea.ft")
+	require.NoError(t, err)
+	assert.Equal(t, string(scenario), string(data), "ignored files are not tagged")
+}
+
+// @ft:273
+func TestSync_RenamedFileKeepsHistory(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n"), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "1", "accepted")
+	fx := dbtest.Open(t, "fts/ft.db")
+	fileID := fx.FileID("fts/login.ft")
+	fx.Close()
+
+	tagged, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	require.NoError(t, os.Rename("fts/login.ft", "fts/sign_in.ft"))
+	out := runSync(t)
+
+	assert.Contains(t, out, "ren  fts/login.ft → fts/sign_in.ft")
+	assert.NotContains(t, out, "del ")
+	assert.NotContains(t, out, "new ")
+	assert.NotContains(t, out, "- @ft:")
+
+	data, err := os.ReadFile("fts/sign_in.ft")
+	require.NoError(t, err)
+	assert.Equal(t, string(tagged), string(data), "tags are not rewritten")
+
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, fileID, fx.FileID("fts/sign_in.ft"))
+	assert.Equal(t, 0, fx.CountFilesWithPath("fts/login.ft"))
+	assert.Equal(t, 2, fx.CountScenarios())
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+}
+
+// @ft:274
+func TestSync_RenamedUntaggedCopyMatchedByContent(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	original := "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(original), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "2", "accepted")
+
+	require.NoError(t, os.Remove("fts/login.ft"))
+	require.NoError(t, os.WriteFile("fts/sign_in.ft", []byte(original), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "ren  fts/login.ft → fts/sign_in.ft")
+
+	data, err := os.ReadFile("fts/sign_in.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "@ft:1\n  Scenario: User logs in")
+	assert.Contains(t, string(data), "@ft:2\n  Scenario: User logs out")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 2, fx.CountScenarios())
+	assert.Equal(t, "accepted", fx.LatestStatusByID(2))
+}
+
+// @ft:275
+func TestSync_FileMovedIntoDirectoryAndEdited(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/invoices.ft", []byte("Feature: Invoices\n  Scenario: Invoice is sent\n    Given an order\n"), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "1", "accepted")
+
+	require.NoError(t, os.MkdirAll("fts/billing", 0o755))
+	require.NoError(t, os.Remove("fts/invoices.ft"))
+	require.NoError(t, os.WriteFile("fts/billing/invoices.ft", []byte("Feature: Invoices\n  @ft:1\n  Scenario: Invoice is sent\n    Given a paid order\n"), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "ren  fts/invoices.ft → fts/billing/invoices.ft\n       ~ @ft:1 Invoice is sent")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "modified", fx.LatestStatusByID(1))
+}
+
+// @ft:276
+func TestSync_UnrelatedFilesAreNotRenames(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.Remove("fts/login.ft"))
+	require.NoError(t, os.WriteFile("fts/checkout.ft", []byte("Feature: Checkout\n  Scenario: User logs in\n    Given a cart\n"), 0o644))
+	out := runSync(t)
+
+	assert.NotContains(t, out, "ren ")
+	assert.Contains(t, out, "new  fts/checkout.ft")
+	assert.Contains(t, out, "del  fts/login.ft")
+}
+
+// @ft:277
+func TestSync_ScenarioMovedBetweenTrackedFiles(t *testing.T) {
+	for _, tc := range []struct{ from, to string }{
+		{"fts/a.ft", "fts/b.ft"},
+		{"fts/z.ft", "fts/b.ft"},
+	} {
+		t.Run(tc.from, func(t *testing.T) {
+			inTempDir(t)
+			runInit(t)
+			require.NoError(t, os.WriteFile(tc.from, []byte("Feature: From\n  Scenario: Stays\n    Given a user\n\n  Scenario: Travels\n    Given a user\n"), 0o644))
+			require.NoError(t, os.WriteFile(tc.to, []byte("Feature: To\n  Scenario: Resident\n    Given a user\n"), 0o644))
+			runSync(t)
+			fx := dbtest.Open(t, "fts/ft.db")
+			id, _ := fx.ScenarioByName("Travels")
+			fx.Close()
+			runStatusUpdate(t, fmt.Sprint(id), "accepted")
+
+			from, err := os.ReadFile(tc.from)
+			require.NoError(t, err)
+			head, travels, ok := strings.Cut(string(from), "\n\n")
+			require.True(t, ok)
+			require.NoError(t, os.WriteFile(tc.from, []byte(head+"\n"), 0o644))
+			to, err := os.ReadFile(tc.to)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(tc.to, []byte(string(to)+"\n"+travels), 0o644))
+
+			out := runSync(t)
+
+			assert.Contains(t, out, fmt.Sprintf("> @ft:%d Travels (from %s)", id, tc.from))
+			assert.Contains(t, out, "mod  "+tc.to)
+			assert.NotContains(t, out, "- @ft:")
+			assert.NotContains(t, out, "+ @ft:")
+
+			fx = dbtest.Open(t, "fts/ft.db")
+			_, fileID, _, _ := fx.ScenarioMeta(id)
+			assert.Equal(t, fx.FileID(tc.to), fileID)
+			assert.Equal(t, "accepted", fx.LatestStatusByID(id))
+			assert.Equal(t, 3, fx.CountScenarios())
+
+			data, err := os.ReadFile(tc.to)
+			require.NoError(t, err)
+			assert.Equal(t, 1, strings.Count(string(data), fmt.Sprintf("@ft:%d\n", id)), "tag is kept, not rewritten")
+		})
+	}
+}
+
+// @ft:278
+func TestSync_ScenarioMovedIntoNewFileAndEdited(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User resets password\n    Given a user\n"), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "2", "accepted")
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/passwords.ft", []byte("Feature: Passwords\n  @ft:2\n  Scenario: User resets password\n    Given a user with an email\n"), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "new  fts/passwords.ft\n       > @ft:2 User resets password (from fts/login.ft)")
+	assert.NotContains(t, out, "- @ft:2")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	_, fileID, _, _ := fx.ScenarioMeta(2)
+	assert.Equal(t, fx.FileID("fts/passwords.ft"), fileID)
+	assert.Equal(t, "modified", fx.LatestStatusByID(2))
+	assert.Equal(t, 1, fx.CountStatusesByStatus(2, "accepted"))
+}
+
+// @ft:279
+func TestSync_FailureRollsBackWholePass(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	login := "Feature: Login\n  Scenario: User logs in\n    Given a user\n"
+	signup := "Feature: Signup\n  Scenario: Boom\n    Given a visitor\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(login), 0o644))
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte(signup), 0o644))
+	fx := dbtest.Open(t, "fts/ft.db")
+	fx.FailInserts("scenarios", "Boom")
+	fx.Close()
+
+	var buf bytes.Buffer
+	err := RunSync(&buf, SyncOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "injected failure")
+
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountFiles())
+	assert.Equal(t, 0, fx.CountScenarios())
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, login, string(data), "no tags written to files synced before the failure")
+}
+
+// @ft:280
+func TestSync_FailureLeavesTrackedStateUntouched(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	runStatusUpdate(t, "1", "accepted")
+	statuses, err := os.ReadFile("fts/statuses.csv")
+	require.NoError(t, err)
+
+	edited := "Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user with a password\n\n  Scenario: Boom\n    Given a user\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(edited), 0o644))
+	fx := dbtest.Open(t, "fts/ft.db")
+	fx.FailInserts("scenarios", "Boom")
+	fx.Close()
+
+	var buf bytes.Buffer
+	require.Error(t, RunSync(&buf, SyncOptions{}))
+
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "  Scenario: User logs in\n    Given a user", fx.ScenarioContent(1).String)
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+	assert.Equal(t, 1, fx.CountScenarios())
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, edited, string(data))
+	after, err := os.ReadFile("fts/statuses.csv")
+	require.NoError(t, err)
+	assert.Equal(t, string(statuses), string(after))
+}
+
+// @ft:281
+func TestSync_ReportsTestLinkWriteErrors(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:1\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+	fx := dbtest.Open(t, "fts/ft.db")
+	fx.FailInserts("test_links", "")
+	fx.Close()
+
+	var buf bytes.Buffer
+	err := RunSync(&buf, SyncOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "syncing test links")
+
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountScenarios())
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "@ft:1")
+}
+
+// @ft:282
+func TestSync_DryRunReportsWithoutWriting(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	login := "Feature: Login\n  Scenario: User logs in\n    Given a user\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(login), 0o644))
+
+	out := runSyncWith(t, SyncOptions{DryRun: true})
+
+	assert.Contains(t, out, "new  fts/login.ft\n       + @ft:1 User logs in")
+	assert.Contains(t, out, "tags to write:\n  fts/login.ft:2 @ft:1")
+	assert.Contains(t, out, "files to write:\n  fts/login.ft")
+	assert.Contains(t, out, "would sync 1 files, 1 scenarios (dry run, nothing written)")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountFiles())
+	assert.Equal(t, 0, fx.CountScenarios())
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, login, string(data))
+}
+
+// @ft:283
+func TestSync_DryRunReportsStatusesAndTestLinks(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	runStatusUpdate(t, "1", "accepted")
+	statuses, err := os.ReadFile("fts/statuses.csv")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user with a password\n"), 0o644))
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:1\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+
+	out := runSyncWith(t, SyncOptions{DryRun: true})
+
+	assert.Contains(t, out, "       ~ @ft:1 User logs in")
+	assert.Contains(t, out, "statuses to add:\n  @ft:1 modified")
+	assert.Contains(t, out, "test links:\n  + @ft:1 login_test.go:3")
+	assert.Contains(t, out, "files to write:\n  fts/statuses.csv")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+	assert.Equal(t, 0, fx.CountTestLinks())
+	assert.Equal(t, "  Scenario: User logs in\n    Given a user", fx.ScenarioContent(1).String)
+	after, err := os.ReadFile("fts/statuses.csv")
+	require.NoError(t, err)
+	assert.Equal(t, string(statuses), string(after))
+}
+
+// @ft:284
+func TestSync_CheckFailsOnUntaggedScenario(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(string(data)+"\n  Scenario: User logs out\n    Given a user\n"), 0o644))
+
+	var buf bytes.Buffer
+	err = RunSync(&buf, SyncOptions{Check: true})
+
+	assert.ErrorIs(t, err, errOutOfSync)
+	assert.Contains(t, buf.String(), "tags to write:\n  fts/login.ft:6 @ft:2")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenarios())
+}
+
+// @ft:285
+func TestSync_CheckFailsOnContentDrift(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user with a password\n"), 0o644))
+
+	var buf bytes.Buffer
+	err := RunSync(&buf, SyncOptions{Check: true})
+
+	assert.ErrorIs(t, err, errOutOfSync)
+	assert.Contains(t, buf.String(), "mod  fts/login.ft\n       ~ @ft:1 User logs in")
+}
+
+// @ft:286
+func TestSync_CheckPassesWhenInSync(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	runStatusUpdate(t, "1", "accepted")
+
+	var buf bytes.Buffer
+	require.NoError(t, RunSync(&buf, SyncOptions{Check: true}))
+
+	// A fresh clone rebuilds the database from the committed files
+	require.NoError(t, os.Remove("fts/ft.db"))
+	runInit(t)
+	buf.Reset()
+	require.NoError(t, RunSync(&buf, SyncOptions{Check: true}))
+	assert.Contains(t, buf.String(), "new  fts/login.ft")
+}
+
+// @ft:292
+func TestSync_RehydratesRemovedScenarioWithTestLinks(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+	runStatusUpdate(t, "2", "accepted")
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "mod  fts/login.ft\n       ! @ft:2 User logs out (restored: has test links)")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "# ft error: scenario(s) @ft:2 restored because active test links exist — remove tests before removing scenarios (line 7)\nFeature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n\n  @ft:2\n  Scenario: User logs out\n    Given a user\n", string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "accepted", fx.LatestStatusByID(2))
+	assert.Equal(t, 1, fx.CountTestLinksForScenario(2))
+}
+
+// @ft:293
+func TestSync_RebuildsDeletedFileWithTestLinks(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.Remove("fts/login.ft"))
+	out := runSync(t)
+
+	assert.Contains(t, out, "rst  fts/login.ft\n")
+	assert.Contains(t, out, "       ! @ft:2 User logs out (restored: has test links)")
+	assert.Contains(t, out, "       - @ft:1 User logs in")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login\n\n  @ft:2\n  Scenario: User logs out\n    Given a user\n", string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.False(t, fx.FileDeleted("fts/login.ft"))
+	assert.Equal(t, 0, fx.CountScenariosByID(1))
+}
+
+// @ft:294
+func TestSync_ReportsConflictForTestedScenario(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n\n// @ft:2\nfunc TestLogoutTwice(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	out := runSync(t)
+
+	assert.Contains(t, out, "cfl  @ft:2 User logs out — still linked from login_test.go:3, login_test.go:6\n")
+	assert.Contains(t, out, "run `ft sync --force` to remove them anyway")
+	assert.NotContains(t, out, "- @ft:2")
+}
+
+// @ft:295
+func TestSync_ForceRemovesTestedScenario(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	edited := "Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(edited), 0o644))
+	out := runSyncWith(t, SyncOptions{Force: true})
+
+	assert.Contains(t, out, "       - @ft:2 User logs out")
+	assert.NotContains(t, out, "cfl")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, edited, string(data))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "removed", fx.LatestStatusByID(2), "kept with a removed status so its links stay visible")
+	assert.Equal(t, 1, fx.CountTestLinksForScenario(2))
+	fx.Close()
+
+	report := runStatusReport(t)
+	assert.Contains(t, report, "Orphaned test links: 1\n  login_test.go:3 → @ft:2 User logs out (removed)\n")
+}
+
+// @ft:296
+func TestSync_ForceDeletesFileWithTestedScenario(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:1\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.Remove("fts/login.ft"))
+	out := runSyncWith(t, SyncOptions{Force: true})
+
+	assert.Contains(t, out, "del  fts/login.ft\n       - @ft:1 User logs in")
+	_, err := os.Stat("fts/login.ft")
+	assert.True(t, os.IsNotExist(err))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.True(t, fx.FileDeleted("fts/login.ft"))
+	assert.Equal(t, "removed", fx.LatestStatusByID(1))
+}
+
+// @ft:297
+func TestSync_DuplicateTagAcrossFilesIsReported(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	copied := `Feature: Signup
+  @ft:1
+  Scenario: User signs up
+    Given a visitor
+`
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte(copied), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "err  fts/signup.ft:2:3 — duplicate @ft:1, also at fts/login.ft:2")
+	assert.Contains(t, out, "run `ft sync --fix-duplicates`")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "User logs in", fx.ScenarioName(1))
+	assert.Equal(t, 0, fx.CountScenariosByName("User signs up"))
+	require.Equal(t, 1, fx.CountDiagnostics("fts/signup.ft"))
+	line, column, severity, _ := fx.Diagnostic("fts/signup.ft")
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 3, column)
+	assert.Equal(t, "error", severity)
+
+	data, err := os.ReadFile("fts/signup.ft")
+	require.NoError(t, err)
+	assert.Equal(t, copied, string(data))
+}
+
+// @ft:298
+func TestSync_DuplicateTagInOneFileKeepsTrackedScenario(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	runStatusUpdate(t, "1", "accepted")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  @ft:1
+  Scenario: User logs in again
+    Given a returning user
+
+  @ft:1
+  Scenario: User logs in
+    Given a user
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "trk  fts/login.ft")
+	assert.Contains(t, out, "err  fts/login.ft:2:3 — duplicate @ft:1, also at fts/login.ft:6")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "User logs in", fx.ScenarioName(1))
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+	assert.Equal(t, 1, fx.CountScenarios())
+}
+
+// @ft:299
+func TestSync_FixDuplicatesRetagsCopy(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	runStatusUpdate(t, "1", "accepted")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  @ft:1
+  Scenario: User logs in
+    Given a user
+
+  @ft:1 @smoke
+  Scenario: User logs in with a token
+    Given a user with a token
+`), 0o644))
+
+	out := runSyncWith(t, SyncOptions{FixDuplicates: true})
+
+	assert.Contains(t, out, "mod  fts/login.ft")
+	assert.Contains(t, out, "+ @ft:2 User logs in with a token (was duplicate @ft:1)")
+	assert.NotContains(t, out, "duplicate @ft:1, also at")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "User logs in", fx.ScenarioName(1))
+	assert.Equal(t, "accepted", fx.LatestStatusByID(1))
+	assert.Equal(t, "User logs in with a token", fx.ScenarioName(2))
+	assert.Equal(t, 0, fx.CountDiagnostics("fts/login.ft"))
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "  @ft:2 @smoke\n  Scenario: User logs in with a token")
+
+	out = runSync(t)
+	assert.Contains(t, out, "trk  fts/login.ft")
+}
+
+// @ft:300
+func TestSync_DuplicateTagKeepsTrackedFileOverPathOrder(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User logs in
+    Given a user
+`), 0o644))
+	runSync(t)
+	require.NoError(t, os.WriteFile("fts/auth.ft", []byte(`Feature: Auth
+  @ft:1
+  Scenario: User logs in
+    Given a user
+`), 0o644))
+
+	out := runSyncWith(t, SyncOptions{FixDuplicates: true})
+
+	assert.Contains(t, out, "+ @ft:2 User logs in (was duplicate @ft:1)")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	_, fileID, _, _ := fx.ScenarioMeta(1)
+	assert.Equal(t, fx.FileID("fts/login.ft"), fileID)
+	_, fileID, _, _ = fx.ScenarioMeta(2)
+	assert.Equal(t, fx.FileID("fts/auth.ft"), fileID)
+}
+
+// @ft:301
+func TestSync_EditedUntaggedScenarioMatchedBySimilarity(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+    When  they enter their password
+    Then  they see the dashboard
+`)
+	runStatusUpdate(t, "1", "accepted")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User signs in
+    Given a registered user
+    When  they enter their password
+    Then  they see their dashboard
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "mod  fts/login.ft")
+	assert.Contains(t, out, `~ @ft:1 User signs in (matched "User logs in", 81% similar)`)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenarios())
+	assert.Equal(t, "User signs in", fx.ScenarioName(1))
+	assert.Equal(t, "modified", fx.LatestStatusByID(1))
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "@ft:1\n  Scenario: User signs in")
+}
+
+// @ft:302
+func TestSync_DissimilarUntaggedScenarioIsNew(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+    When  they enter their password
+    Then  they see the dashboard
+`)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: Admin exports reports
+    Given an administrator
+    When  they export last month's orders
+    Then  a CSV file is downloaded
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "+ @ft:2 Admin exports reports")
+	assert.Contains(t, out, "- @ft:1 User logs in")
+	assert.NotContains(t, out, "matched")
+}
+
+// @ft:303
+func TestSync_MatchThresholdIsConfigurable(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+    When  they enter their password
+    Then  they see the dashboard
+`)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User signs in
+    Given a registered user
+    When  they enter their password
+    Then  they see their dashboard
+`), 0o644))
+
+	var buf bytes.Buffer
+	require.EqualError(t, RunSync(&buf, SyncOptions{MatchThreshold: 1.5}), "match threshold must be between 0 and 1, got 1.5")
+
+	out := runSyncWith(t, SyncOptions{MatchThreshold: 0.9})
+
+	assert.Contains(t, out, "+ @ft:2 User signs in")
+	assert.Contains(t, out, "- @ft:1 User logs in")
+}
+
+// @ft:304
+func TestSync_SimilarityPairsEachScenarioWithItsBestMatch(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+    When  they enter their password
+    Then  they see the dashboard
+
+  Scenario: User logs out
+    Given a logged in user
+    When  they click log out
+    Then  they see the login page
+`)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(`Feature: Login
+  Scenario: User signs out
+    Given a signed in user
+    When  they click sign out
+    Then  they see the login page
+
+  Scenario: User signs in
+    Given a registered user
+    When  they enter their password
+    Then  they see their dashboard
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, `~ @ft:2 User signs out (matched "User logs out"`)
+	assert.Contains(t, out, `~ @ft:1 User signs in (matched "User logs in"`)
+	assert.Less(t, strings.Index(out, "User signs out"), strings.Index(out, "User signs in"))
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 2, fx.CountScenarios())
+}
+
+// backdate sets the modification time of each path an hour into the past,
+// far enough for sync to trust it.
+func backdate(t *testing.T, paths ...string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	for _, path := range paths {
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+}
+
+// rewriteKeepingStamp replaces old with new in path, keeping the file's
+// size and modification time, so only its content tells that it changed.
+func rewriteKeepingStamp(t *testing.T, path, old, new string) {
+	t.Helper()
+	require.Equal(t, len(old), len(new))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), old, new, 1)), 0o644))
+	require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+}
+
+// @ft:305
+func TestSync_UnchangedFileIsSkippedUntilFull(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	backdate(t, "fts/login.ft")
+	runSync(t)
+	rewriteKeepingStamp(t, "fts/login.ft", "Given a user", "Given an ant")
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "trk  fts/login.ft")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Contains(t, fx.ScenarioContent(1).String, "Given a user")
+	fx.Close()
+
+	out = runSyncWith(t, SyncOptions{Full: true})
+
+	assert.Contains(t, out, "mod  fts/login.ft")
+	assert.Contains(t, out, "~ @ft:1 User logs in")
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Contains(t, fx.ScenarioContent(1).String, "Given an ant")
+}
+
+// @ft:306
+func TestSync_ChangedFileIsReconciledBesideSkippedOnes(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte("Feature: Signup\n  Scenario: User signs up\n    Given a visitor\n"), 0o644))
+	runSync(t)
+	backdate(t, "fts/login.ft", "fts/signup.ft")
+	runSync(t)
+
+	data, err := os.ReadFile("fts/signup.ft")
+	require.NoError(t, err)
+	updated := string(data) + "\n  Scenario: User confirms email\n    Given a new user\n"
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte(updated), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "trk  fts/login.ft")
+	assert.Contains(t, out, "mod  fts/signup.ft")
+	assert.Contains(t, out, "+ @ft:3 User confirms email")
+	assert.Contains(t, out, "synced 2 files, 1 scenarios")
+
+	out = runSync(t)
+	assert.Contains(t, out, "trk  fts/signup.ft")
+}
+
+// @ft:307
+func TestSync_UnchangedTestFileIsReadFromCacheUntilFull(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+
+  Scenario: User logs out
+    Given a user
+`)
+	require.NoError(t, os.WriteFile("login_test.go", []byte(`package main
+
+// @ft:1
+func TestLogin(t *testing.T) {}
+`), 0o644))
+	runSync(t)
+	backdate(t, "login_test.go")
+	runSync(t)
+	rewriteKeepingStamp(t, "login_test.go", "@ft:1", "@ft:2")
+
+	runSync(t)
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountTestLinksForScenario(1))
+	assert.Equal(t, 0, fx.CountTestLinksForScenario(2))
+	fx.Close()
+
+	runSyncWith(t, SyncOptions{Full: true})
+
+	fx = dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountTestLinksForScenario(1))
+	assert.Equal(t, 1, fx.CountTestLinksForScenario(2))
+}
+
+// @ft:308
+func TestSync_ChangedFileCopyingUnchangedFileTagIsStillDuplicate(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, `Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	backdate(t, "fts/login.ft")
+	runSync(t)
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte(`Feature: Signup
+  @ft:1
+  Scenario: User signs up
+    Given a visitor
+`), 0o644))
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "trk  fts/login.ft")
+	assert.Contains(t, out, "err  fts/signup.ft:2:3 — duplicate @ft:1, also at fts/login.ft:2")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, "User logs in", fx.ScenarioName(1))
+}
+
+// @ft:309
+func TestSync_FileWithErrorsIsNeverSkipped(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n\n    Examples: Orphaned\n      | a |\n"), 0o644))
+	runSync(t)
+	backdate(t, "fts/login.ft")
+	runSync(t)
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "err  fts/login.ft:5:5 — Examples must belong to a Scenario Outline")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountDiagnostics("fts/login.ft"))
+}
+
+// writeParallelProject writes a project of many .ft and test files, with a
+// new scenario, a moved one, a duplicate tag and a broken file among them.
+func writeParallelProject(t *testing.T) {
+	t.Helper()
+	for i := range 40 {
+		content := fmt.Sprintf("Feature: Area %d\n  Scenario: Thing %d works\n    Given thing %d\n", i, i, i)
+		require.NoError(t, os.WriteFile(fmt.Sprintf("fts/area_%02d.ft", i), []byte(content), 0o644))
+		test := fmt.Sprintf("package pkg\n\nimport \"testing\"\n\n// @ft:%d\nfunc TestThing%d(t *testing.T) {}\n", i+1, i)
+		require.NoError(t, os.MkdirAll(fmt.Sprintf("pkg%d", i%4), 0o755))
+		require.NoError(t, os.WriteFile(fmt.Sprintf("pkg%d/thing%d_test.go", i%4, i), []byte(test), 0o644))
+	}
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/area_03.ft", []byte("Feature: Area 3\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/area_07.ft", []byte(`Feature: Area 7
+  @ft:8
+  Scenario: Thing 7 works
+    Given thing 7
+
+  @ft:4
+  Scenario: Thing 3 works
+    Given thing 3
+
+  Scenario: Thing 40 works
+    Given thing 40
+`), 0o644))
+	require.NoError(t, os.WriteFile("fts/area_11.ft", []byte(`Feature: Area 11
+  @ft:12
+  Scenario: Thing 11 works
+    Given thing 11
+
+  @ft:12
+  Scenario: Thing 11 works again
+    Given thing 11 again
+`), 0o644))
+	require.NoError(t, os.WriteFile("fts/area_19.ft", []byte("Feature: Area 19\n  Scenario: Thing 19 works\n    Given thing 19\n\n    Examples: Orphaned\n      | a |\n"), 0o644))
+}
+
+// @ft:311
+func TestSync_ParallelOutputMatchesSerial(t *testing.T) {
+	run := func(procs int) (string, map[string]string) {
+		inTempDir(t)
+		runInit(t)
+		writeParallelProject(t)
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		out := runSync(t)
+		files := make(map[string]string)
+		paths, err := filepath.Glob("fts/*")
+		require.NoError(t, err)
+		for _, path := range paths {
+			if filepath.Ext(path) == ".db" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			files[path] = string(data)
+		}
+		return out, files
+	}
+
+	serialOut, serialFiles := run(1)
+	parallelOut, parallelFiles := run(8)
+
+	assert.Contains(t, serialOut, "err  fts/area_11.ft")
+	assert.Equal(t, serialOut, parallelOut)
+	assert.Equal(t, serialFiles, parallelFiles)
+}
+
+// @ft:312
+func TestSync_FailsWhileAnotherSyncHoldsTheLock(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	lock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	var buf bytes.Buffer
+	err = RunSync(&buf, SyncOptions{})
+
+	require.ErrorIs(t, err, db.ErrLocked)
+	assert.Contains(t, err.Error(), fmt.Sprintf("another ft sync is running: fts/sync.lock is held by another process (pid %d)", os.Getpid()))
+	assert.Contains(t, err.Error(), "pass --wait")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountFiles())
+}
+
+// @ft:350
+func TestSync_DoesNotMigrateWhileAnotherSyncHoldsTheLock(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	fx := dbtest.Open(t, "fts/ft.db")
+	fx.SetSchemaVersion(len(db.All) - 1)
+	fx.Close()
+	lock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	var buf bytes.Buffer
+	err = RunSync(&buf, SyncOptions{})
+
+	require.ErrorIs(t, err, db.ErrLocked, "the store isn't opened, so nothing is migrated")
+}
+
+// @ft:351
+func TestSync_FailsBeforeInit(t *testing.T) {
+	inTempDir(t)
+
+	var buf bytes.Buffer
+	require.ErrorIs(t, RunSync(&buf, SyncOptions{}), db.ErrNotInitialized)
+	assert.NoDirExists(t, "fts")
+}
+
+// @ft:313
+func TestSync_WaitsForTheLock(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	lock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		lock.Unlock()
+	}()
+
+	out := runSyncWith(t, SyncOptions{Wait: 5 * time.Second})
+
+	assert.Contains(t, out, "new  fts/login.ft")
+	assert.NoFileExists(t, "fts/sync.lock")
+}
+
+// @ft:314
+func TestSync_GivesUpWaitingForTheLock(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	lock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	var buf bytes.Buffer
+	err = RunSync(&buf, SyncOptions{Wait: 100 * time.Millisecond})
+
+	require.ErrorIs(t, err, db.ErrLocked)
+	assert.Contains(t, err.Error(), "gave up after waiting 100ms")
+}
+
+// gitRun runs git in the current directory, failing the test on error.
+func gitRun(t *testing.T, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=ft", "-c", "user.email=ft@example.com", "-c", "commit.gpgsign=false"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// setupChangedProject syncs fts/login.ft and fts/signup.ft, commits them
+// and trusts their stamps.
+func setupChangedProject(t *testing.T) {
+	t.Helper()
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte("Feature: Signup\n  Scenario: User signs up\n    Given a visitor\n"), 0o644))
+	runSync(t)
+	backdate(t, "fts/login.ft", "fts/signup.ft")
+	runSync(t)
+	gitRun(t, "init", "-q")
+	gitRun(t, "add", "-A")
+	gitRun(t, "commit", "-q", "-m", "initial")
+}
+
+// @ft:322
+func TestSync_SinceReconcilesOnlyFilesChangedSinceRef(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupChangedProject(t)
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte("Feature: Signup\n  @ft:2\n  Scenario: User signs up\n    Given a visitor\n\n  Scenario: User confirms\n    Given an email\n"), 0o644))
+	gitRun(t, "commit", "-q", "-am", "add confirm")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a session\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/billing.ft", []byte("Feature: Billing\n  Scenario: User pays\n    Given an invoice\n"), 0o644))
+
+	changed, err := changedSince("HEAD")
+	require.NoError(t, err)
+	out := runSyncWith(t, SyncOptions{OnlyChanged: true, Changed: changed})
+
+	assert.ElementsMatch(t, []string{"fts/login.ft", "fts/billing.ft"}, changed)
+	assert.Contains(t, out, "mod  fts/login.ft")
+	assert.Contains(t, out, "new  fts/billing.ft")
+	assert.Contains(t, out, "trk  fts/signup.ft")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenariosByName("User logs out"))
+	assert.Equal(t, 0, fx.CountScenariosByName("User confirms"))
+}
+
+// @ft:323
+func TestSync_ChangedFilesReconcilesOnlyListedFiles(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupChangedProject(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a session\n"), 0o644))
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte("Feature: Signup\n  @ft:2\n  Scenario: User signs up\n    Given a visitor\n\n  Scenario: User confirms\n    Given an email\n"), 0o644))
+
+	changed, err := readChangedFiles("-", strings.NewReader("./fts/login.ft\n\nREADME.md\n"))
+	require.NoError(t, err)
+	out := runSyncWith(t, SyncOptions{OnlyChanged: true, Changed: changed})
+
+	assert.Contains(t, out, "mod  fts/login.ft")
+	assert.Contains(t, out, "trk  fts/signup.ft")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenariosByName("User logs out"))
+	assert.Equal(t, 0, fx.CountScenariosByName("User confirms"))
+}
+
+// @ft:324
+func TestSync_ChangedFilesStillHandlesDeletions(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupChangedProject(t)
+	require.NoError(t, os.Remove("fts/signup.ft"))
+
+	out := runSyncWith(t, SyncOptions{OnlyChanged: true})
+
+	assert.Contains(t, out, "trk  fts/login.ft")
+	assert.Contains(t, out, "del  fts/signup.ft")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.True(t, fx.FileDeleted("fts/signup.ft"))
+}
+
+// @ft:325
+func TestSync_ChangedFilesLimitsTestScanning(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupChangedProject(t)
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package login\n\nimport \"testing\"\n\n// @ft:1\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+	require.NoError(t, os.WriteFile("signup_test.go", []byte("package login\n\nimport \"testing\"\n\n// @ft:2\nfunc TestSignup(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package login\n\nimport \"testing\"\n\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+	require.NoError(t, os.WriteFile("signup_test.go", []byte("package login\n\nimport \"testing\"\n\nfunc TestSignup(t *testing.T) {}\n"), 0o644))
+
+	runSyncWith(t, SyncOptions{OnlyChanged: true, Changed: []string{"signup_test.go"}})
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountTestLinksForScenario(1))
+	assert.Equal(t, 0, fx.CountTestLinksForScenario(2))
+}
+
+// @ft:326
+func TestSync_TagWriteKeepsBOM(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("\uFEFFFeature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "\uFEFFFeature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n", string(data))
+}
+
+// @ft:327
+func TestSync_ErrorCommentGoesAfterBOM(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("\uFEFFFeature: Login\r\n  Examples: Orphaned\r\n    | a |\r\n"), 0o644))
+
+	runSyncWith(t, SyncOptions{WriteErrors: true})
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "\uFEFF# ft error: Examples must belong to a Scenario Outline (line 2)\r\nFeature: Login\r\n  Examples: Orphaned\r\n    | a |\r\n", string(data))
+}
+
+// @ft:328
+func TestSync_ErrorCommentRemovalKeepsLineEndingsAndBOM(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("\uFEFF# ft error: Examples must belong to a Scenario Outline (line 3)\r\nFeature: Login\r\n  @ft:1\r\n  Scenario: User logs in\r\n    Given a user"), 0o644))
+
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "\uFEFFFeature: Login\r\n  @ft:1\r\n  Scenario: User logs in\r\n    Given a user", string(data))
+}
+
+// @ft:329
+func TestSync_RehydrationKeepsLineEndingsAndMissingFinalNewline(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\r\n  Scenario: User logs in\r\n    Given a user\r\n\r\n  Scenario: User logs out\r\n    Given a user\r\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\r\n  @ft:1\r\n  Scenario: User logs in\r\n    Given a user"), 0o644))
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "# ft error: scenario(s) @ft:2 restored because active test links exist — remove tests before removing scenarios (line 7)\r\nFeature: Login\r\n  @ft:1\r\n  Scenario: User logs in\r\n    Given a user\r\n\r\n  @ft:2\r\n  Scenario: User logs out\r\n    Given a user", string(data))
+}
+
+// @ft:330
+func TestSync_TagWriteUsesFilesMostCommonLineEnding(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\r\n    Given a user\r\n"), 0o644))
+
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login\n  @ft:1\r\n  Scenario: User logs in\r\n    Given a user\r\n", string(data))
+}
+
+// @ft:331
+func TestSync_JSONReportsNewFileScenariosAndTags(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n  Scenario: User logs out\n    Given a user\n"), 0o644))
+
+	out := runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Equal(t, `{"event":"file","action":"new","path":"fts/login.ft"}
+{"event":"scenario","action":"new","id":1,"name":"User logs in","path":"fts/login.ft"}
+{"event":"scenario","action":"new","id":2,"name":"User logs out","path":"fts/login.ft"}
+{"event":"tag","path":"fts/login.ft","line":2,"id":1}
+{"event":"tag","path":"fts/login.ft","line":4,"id":2}
+{"event":"write","path":"fts/login.ft"}
+{"event":"summary","files":1,"scenarios":2,"dry_run":false}
+`, out)
+}
+
+// @ft:332
+func TestSync_JSONReportsStatusesAndTestLinks(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	runStatusUpdate(t, "1", "accepted")
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user with a password\n"), 0o644))
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:1\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+	out := runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Equal(t, `{"event":"file","action":"modified","path":"fts/login.ft"}
+{"event":"scenario","action":"modified","id":1,"name":"User logs in","path":"fts/login.ft"}
+{"event":"status","id":1,"status":"modified"}
+{"event":"test_link","action":"added","id":1,"path":"login_test.go","line":3}
+{"event":"write","path":"fts/statuses.csv"}
+{"event":"summary","files":1,"scenarios":1,"dry_run":false}
+`, out)
+
+	require.NoError(t, os.Remove("login_test.go"))
+	out = runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Contains(t, out, `{"event":"test_link","action":"removed","id":1,"path":"login_test.go","line":3}`+"\n")
+}
+
+// @ft:333
+func TestSync_JSONReportsDiagnostics(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n\n    Examples: Orphaned\n      | a |\n"), 0o644))
+
+	out := runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Contains(t, out, `{"event":"diagnostic","path":"fts/login.ft","line":5,"end_line":6,"column":5,"severity":"error","message":"Examples must belong to a Scenario Outline"}`+"\n")
+	assert.NotContains(t, out, "err  ")
+}
+
+// brokenWriter fails every write, like a closed pipe.
+type brokenWriter struct{}
+
+func (brokenWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+// @ft:352
+func TestSync_JSONFailsWhenOutputCantBeWritten(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+
+	err := RunSync(brokenWriter{}, SyncOptions{JSON: true})
+
+	require.EqualError(t, err, "writing JSON output: broken pipe")
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "@ft:1", "the sync itself still happened")
+}
+
+// @ft:334
+func TestSync_JSONDryRunWritesNothing(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	login := "Feature: Login\n  Scenario: User logs in\n    Given a user\n"
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte(login), 0o644))
+
+	out := runSyncWith(t, SyncOptions{JSON: true, DryRun: true})
+
+	assert.Equal(t, `{"event":"file","action":"new","path":"fts/login.ft"}
+{"event":"scenario","action":"new","id":1,"name":"User logs in","path":"fts/login.ft"}
+{"event":"tag","path":"fts/login.ft","line":2,"id":1}
+{"event":"write","path":"fts/login.ft"}
+{"event":"summary","files":1,"scenarios":1,"dry_run":true}
+`, out)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, login, string(data))
+}
+
+// @ft:335
+func TestSync_JSONReportsRestoredScenariosAndConflicts(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	out := runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Contains(t, out, `{"event":"scenario","action":"rehydrated","id":2,"name":"User logs out","path":"fts/login.ft"}`+"\n")
+	assert.NotContains(t, out, `"event":"status"`, "a write-back adds no status")
+	assert.Contains(t, out, `{"event":"conflict","id":2,"name":"User logs out","tests":["login_test.go:3"]}`+"\n")
+	assert.NotContains(t, out, "ft sync --force", "hints are left out of JSON")
+}
+
+// @ft:336
+func TestSync_JSONReportsRenamedAndDeletedFiles(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte("Feature: Signup\n  Scenario: User signs up\n    Given a visitor\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.Rename("fts/login.ft", "fts/auth.ft"))
+	require.NoError(t, os.Remove("fts/signup.ft"))
+	out := runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Contains(t, out, `{"event":"file","action":"renamed","path":"fts/auth.ft","from":"fts/login.ft"}`+"\n")
+	assert.Contains(t, out, `{"event":"file","action":"deleted","path":"fts/signup.ft"}`+"\n")
+	assert.Contains(t, out, `{"event":"scenario","action":"removed","id":2,"name":"User signs up","path":"fts/signup.ft"}`+"\n")
+	assert.Contains(t, out, `{"event":"summary","files":2,"scenarios":1,"dry_run":false}`+"\n")
+}
+
+// @ft:337
+func TestSync_LanguageHeaderAfterBOM(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/anmeldung.ft", []byte("\uFEFF# language: de\nFunktionalität: Anmeldung\n  Szenario: Erfolgreiche Anmeldung\n    Wenn er sich anmeldet\n"), 0o644))
+
+	out := runSync(t)
+
+	assert.NotContains(t, out, "err")
+	assert.Contains(t, out, "@ft:1 Erfolgreiche Anmeldung")
+
+	data, err := os.ReadFile("fts/anmeldung.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "\uFEFF# language: de\nFunktionalität: Anmeldung\n  @ft:1\n  Szenario: Erfolgreiche Anmeldung\n    Wenn er sich anmeldet\n", string(data))
+}
+
+// @ft:338
+func TestSync_FeatureLineAfterBOM(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("\uFEFF@smoke\r\nFeature: Login\r\n  Users sign in with a password.\r\n\r\n  Scenario: User logs in\r\n    Given a user\r\n"), 0o644))
+
+	out := runSync(t)
+
+	assert.NotContains(t, out, "err")
+	assert.Contains(t, out, "@ft:1 User logs in")
+
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 0, fx.CountDiagnostics("fts/login.ft"))
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "\uFEFF@smoke\r\nFeature: Login\r\n  Users sign in with a password.\r\n\r\n  @ft:1\r\n  Scenario: User logs in\r\n    Given a user\r\n", string(data))
+}
+
+// @ft:339
+func TestSync_JSONReportsScenarioBackInItsFileAsRestored(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	runStatusUpdate(t, "1", "accepted")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	out := runSyncWith(t, SyncOptions{JSON: true})
+
+	assert.Equal(t, `{"event":"file","action":"modified","path":"fts/login.ft"}
+{"event":"scenario","action":"restored","id":1,"name":"User logs in","path":"fts/login.ft"}
+{"event":"status","id":1,"status":"restored"}
+{"event":"write","path":"fts/statuses.csv"}
+{"event":"summary","files":1,"scenarios":1,"dry_run":false}
+`, out)
+}
+
+// @ft:340
+func TestSync_StatusesRewriteKeepsStatusRecordedAfterCommit(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	runStatusUpdate(t, "1", "accepted")
+
+	store, err := db.OpenProjectStore()
+	require.NoError(t, err)
+	defer store.Close()
+	other, err := db.OpenProjectStore()
+	require.NoError(t, err)
+	defer other.Close()
+
+	// `ft status` waits on the database while the pass runs, then records
+	// its status before the pass gets to rewrite fts/statuses.csv
+	require.NoError(t, store.WithTx(func(tx *db.Store) error {
+		require.NoError(t, tx.AfterCommit(func() error { return other.InsertStatus(1, "implemented") }))
+		return reconcileStatusesFile(tx, &syncPlan{})
+	}))
+
+	rows, err := db.ReadStatusesFile()
+	require.NoError(t, err)
+	assert.Equal(t, []db.StatusRow{{ScenarioID: 1, Status: "implemented"}}, rows)
+}
+
+// @ft:342
+func TestSync_MatchThresholdFlagMustBeInRange(t *testing.T) {
+	for _, threshold := range []float64{0, -0.5, 1.5, math.NaN()} {
+		assert.Error(t, validateMatchThreshold(threshold), "%g", threshold)
+	}
+	assert.EqualError(t, validateMatchThreshold(0), "--match-threshold must be greater than 0 and at most 1, got 0")
+	for _, threshold := range []float64{0.01, defaultMatchThreshold, 1} {
+		assert.NoError(t, validateMatchThreshold(threshold), "%g", threshold)
+	}
+
+	inTempDir(t)
+	runInit(t)
+	var buf bytes.Buffer
+	require.EqualError(t, RunSync(&buf, SyncOptions{MatchThreshold: math.NaN()}), "match threshold must be between 0 and 1, got NaN")
+}
+
+// @ft:344
+func TestSync_RebuildsDeletedFileInDeletedDirectory(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.MkdirAll("fts/billing", 0o755))
+	require.NoError(t, os.WriteFile("fts/billing/pay.ft", []byte("Feature: Pay\n  Scenario: User pays\n    Given a cart\n"), 0o644))
+	runSync(t)
+	require.NoError(t, os.WriteFile("pay_test.go", []byte("package x\n\n// @ft:1\nfunc TestPay(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.RemoveAll("fts/billing"))
+	out := runSync(t)
+
+	assert.Contains(t, out, "rst  fts/billing/pay.ft\n")
+	data, err := os.ReadFile("fts/billing/pay.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "  @ft:1\n  Scenario: User pays\n")
+	assert.Contains(t, runSync(t), "trk  fts/billing/pay.ft\n")
+}
+
+// @ft:346
+func TestSync_RestoredCommentStaysUntilTestLinksAreGone(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n\n  Scenario: User logs out\n    Given a user\n")
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:2\nfunc TestLogout(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	runSync(t)
+	restored := "Feature: Login\n  @ft:1\n  Scenario: User logs in\n    Given a user\n\n  @ft:2\n  Scenario: User logs out\n    Given a user\n"
+	comment := "# ft error: scenario(s) @ft:2 restored because active test links exist — remove tests before removing scenarios (line 7)\n"
+
+	backdate(t, "fts/login.ft")
+	runSync(t)
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, comment+restored, string(data))
+
+	require.NoError(t, os.Remove("login_test.go"))
+	runSync(t)
+
+	data, err = os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, restored, string(data))
+}
+
+// @ft:348
+func TestSync_RehydrationKeepsOtherTags(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	setupScenario(t, "Feature: Login\n  Scenario: User logs in\n    Given a user\n")
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  @ft:1 @smoke\n  @wip\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	require.NoError(t, os.WriteFile("login_test.go", []byte("package x\n\n// @ft:1\nfunc TestLogin(t *testing.T) {}\n"), 0o644))
+	runSync(t)
+
+	require.NoError(t, os.Remove("fts/login.ft"))
+	runSync(t)
+
+	data, err := os.ReadFile("fts/login.ft")
+	require.NoError(t, err)
+	assert.Equal(t, "Feature: Login\n\n  @ft:1 @smoke @wip\n  Scenario: User logs in\n    Given a user\n", string(data))
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval time.Duration
+	watchDebounce time.Duration
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the database in sync as .ft and test files change",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return RunWatch(ctx, cmd.OutOrStdout(), WatchOptions{Interval: watchInterval, Debounce: watchDebounce})
+	},
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", defaultWatchInterval, "how often to check fts/ and test files for changes")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", defaultWatchDebounce, "how long files must stay unchanged before a sync")
+	rootCmd.AddCommand(watchCmd)
+}
+
+const (
+	defaultWatchInterval = time.Second
+	defaultWatchDebounce = 300 * time.Millisecond
+)
+
+// WatchOptions controls RunWatch.
+type WatchOptions struct {
+	// Interval is how often the watched files are checked for changes.
+	// Zero means defaultWatchInterval.
+	Interval time.Duration
+	// Debounce is how long the watched files must stay unchanged after a
+	// change before a sync runs, so a burst of saves syncs once.
+	Debounce time.Duration
+}
+
+// fileState is what ft watch compares to notice a file has changed.
+type fileState struct {
+	size    int64
+	modTime int64
+}
+
+// RunWatch syncs the project once in full, then again whenever a .ft file,
+// fts/.ftignore or a test file changes, until ctx is done. Each pass is an
+// incremental sync whose output leaves out unchanged files. A pass that finds
+// a manual ft sync holding the sync lock waits for it, and one that fails is
+// tried again on the next tick; a pass in progress when ctx is done
+// finishes first.
+func RunWatch(ctx context.Context, w io.Writer, opts WatchOptions) error {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWatchInterval
+	}
+
+	store, err := db.OpenProjectStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	watchLock, err := db.AcquireLock(db.WatchLockName, 0)
+	if errors.Is(err, db.ErrLocked) {
+		return fmt.Errorf("ft watch is already running: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer watchLock.Unlock()
+
+	ui.WatchLine(w, time.Now(), "watching fts/ and test files")
+	prev, err := watchSnapshot()
+	if err != nil {
+		return err
+	}
+	// The first pass re-reads everything, as the daemon may have been
+	// stopped for a while
+	pending := true
+	passOpts := SyncOptions{Full: true}
+	var changedAt time.Time
+	paused := false
+	failed := "" // the error of the last pass, printed once while it repeats
+	fail := func(err error) {
+		if err.Error() != failed {
+			ui.WatchLine(w, time.Now(), "sync failed: "+err.Error())
+			failed = err.Error()
+		}
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		if pending && time.Since(changedAt) >= opts.Debounce {
+			lock, err := db.AcquireLock(db.SyncLockName, 0)
+			switch {
+			case errors.Is(err, db.ErrLocked):
+				if !paused {
+					ui.WatchLine(w, time.Now(), fmt.Sprintf("paused while another sync runs (pid %d)", db.LockHolder(db.SyncLockName)))
+					paused = true
+				}
+			case err != nil:
+				// Left pending, so the next tick tries again
+				fail(err)
+			default:
+				if paused {
+					ui.WatchLine(w, time.Now(), "resumed")
+					paused = false
+				}
+				written, err := runSyncPass(w, store, passOpts)
+				lock.Unlock()
+				// The tags and error comments the pass wrote aren't edits to
+				// sync again; other files changed meanwhile still are
+				if cur, err := watchSnapshot(); err == nil {
+					absorbWrites(prev, cur, written)
+				}
+				if err != nil {
+					fail(err)
+					break
+				}
+				failed = ""
+				pending = false
+				passOpts = SyncOptions{Quiet: true}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			ui.WatchLine(w, time.Now(), "stopped")
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := watchSnapshot()
+		if err != nil {
+			ui.WatchLine(w, time.Now(), err.Error())
+			continue
+		}
+		if !maps.Equal(cur, prev) {
+			prev = cur
+			pending = true
+			changedAt = time.Now()
+		}
+	}
+}
+
+// absorbWrites updates prev with the state in cur of the files a pass
+// wrote, so only changes made by something else trigger the next pass.
+func absorbWrites(prev, cur map[string]fileState, written []string) {
+	for _, path := range written {
+		if st, ok := cur[path]; ok {
+			prev[path] = st
+		} else {
+			delete(prev, path)
+		}
+	}
+}
+
+// watchSnapshot returns the state of every file whose change needs a sync:
+// the .ft files under fts/, fts/.ftignore and the _test.go files.
+func watchSnapshot() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	paths, err := discoverFtFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range append(paths, ftIgnorePath) {
+		if info, err := os.Stat(path); err == nil {
+			snapshot[path] = fileState{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		}
+	}
+	err = walkTestFiles(func(path string, info os.FileInfo) {
+		snapshot[path] = fileState{size: info.Size(), modTime: info.ModTime().UnixNano()}
+	})
+	return snapshot, err
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chriserin/ft/internal/db"
+	"github.com/chriserin/ft/internal/db/dbtest"
+)
+
+// watchOutput is a buffer ft watch writes to while a test reads it.
+type watchOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *watchOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *watchOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// startWatch runs ft watch until the test stops it with the returned
+// function, which returns RunWatch's error.
+func startWatch(t *testing.T, debounce time.Duration) (*watchOutput, func() error) {
+	t.Helper()
+	out := &watchOutput{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunWatch(ctx, out, WatchOptions{Interval: 10 * time.Millisecond, Debounce: debounce})
+	}()
+	stopped := false
+	stop := func() error {
+		if stopped {
+			return nil
+		}
+		stopped = true
+		cancel()
+		return <-done
+	}
+	t.Cleanup(func() { stop() })
+	return out, stop
+}
+
+// waitForOutput waits until the watch output contains want.
+func waitForOutput(t *testing.T, out *watchOutput, want string) {
+	t.Helper()
+	require.Eventually(t, func() bool { return strings.Contains(out.String(), want) }, 5*time.Second, 10*time.Millisecond, "output so far:\n%s", out)
+}
+
+// @ft:316
+func TestWatch_SyncsOnStartup(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+
+	out, stop := startWatch(t, 0)
+	waitForOutput(t, out, "synced 1 files, 1 scenarios")
+	require.NoError(t, stop())
+
+	assert.Contains(t, out.String(), "watching fts/ and test files")
+	assert.Contains(t, out.String(), "new  fts/login.ft")
+	assert.Contains(t, out.String(), "stopped")
+	assert.NoFileExists(t, "fts/watch.lock")
+}
+
+// @ft:317
+func TestWatch_SyncsChangedFiles(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	out, stop := startWatch(t, 0)
+	waitForOutput(t, out, "synced 1 files, 1 scenarios")
+
+	require.NoError(t, os.WriteFile("fts/signup.ft", []byte("Feature: Signup\n  Scenario: User signs up\n    Given a visitor\n"), 0o644))
+	waitForOutput(t, out, "new  fts/signup.ft")
+	require.NoError(t, os.WriteFile("signup_test.go", []byte("package signup\n\nimport \"testing\"\n\n// @ft:2\nfunc TestSignup(t *testing.T) {}\n"), 0o644))
+	require.Eventually(t, func() bool {
+		fx := dbtest.Open(t, "fts/ft.db")
+		defer fx.Close()
+		return fx.CountTestLinksForScenario(2) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, stop())
+
+	after := out.String()[strings.Index(out.String(), "synced 1 files, 1 scenarios"):]
+	assert.NotContains(t, after, "trk  fts/login.ft")
+	data, err := os.ReadFile("fts/signup.ft")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "@ft:2")
+}
+
+// @ft:318
+func TestWatch_DebouncesBurstsOfChanges(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	out, stop := startWatch(t, 300*time.Millisecond)
+	waitForOutput(t, out, "synced 0 files")
+
+	content := "Feature: Login\n"
+	for _, step := range []string{"  Scenario: User logs in\n", "    Given a user\n", "    When they log in\n", "    Then they see the dashboard\n"} {
+		content += step
+		require.NoError(t, os.WriteFile("fts/login.ft", []byte(content), 0o644))
+		time.Sleep(30 * time.Millisecond)
+	}
+	waitForOutput(t, out, "synced 1 files, 1 scenarios")
+	time.Sleep(400 * time.Millisecond)
+	require.NoError(t, stop())
+
+	assert.Equal(t, 1, strings.Count(out.String(), "new  fts/login.ft"))
+	assert.Equal(t, 2, strings.Count(out.String(), "synced "))
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenarios())
+}
+
+// @ft:319
+func TestWatch_PausesWhileAnotherSyncRuns(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	out, stop := startWatch(t, 0)
+	waitForOutput(t, out, "synced 0 files")
+	lock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	waitForOutput(t, out, "paused while another sync runs")
+	assert.NotContains(t, out.String(), "new  fts/login.ft")
+	require.NoError(t, lock.Unlock())
+	waitForOutput(t, out, "new  fts/login.ft")
+	require.NoError(t, stop())
+
+	assert.Contains(t, out.String(), "resumed")
+}
+
+// @ft:320
+func TestWatch_FailsWhenAlreadyRunning(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	lock, err := db.AcquireLock(db.WatchLockName, 0)
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	err = RunWatch(context.Background(), &bytes.Buffer{}, WatchOptions{})
+
+	require.ErrorIs(t, err, db.ErrLocked)
+	assert.Contains(t, err.Error(), "ft watch is already running")
+}
+
+// @ft:321
+func TestSync_WaitsForAPassOfWatch(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	watchLock, err := db.AcquireLock(db.WatchLockName, 0)
+	require.NoError(t, err)
+	defer watchLock.Unlock()
+	syncLock, err := db.AcquireLock(db.SyncLockName, 0)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		syncLock.Unlock()
+	}()
+
+	out := runSync(t)
+
+	assert.Contains(t, out, "new  fts/login.ft")
+}
+
+// @ft:341
+func TestWatch_AbsorbsItsOwnWrites(t *testing.T) {
+	before := fileState{size: 10, modTime: 1}
+	after := fileState{size: 18, modTime: 2}
+	prev := map[string]fileState{"fts/login.ft": before, "fts/signup.ft": before, "fts/gone.ft": before}
+	cur := map[string]fileState{"fts/login.ft": after, "fts/signup.ft": after}
+
+	absorbWrites(prev, cur, []string{"fts/login.ft", "fts/gone.ft", "fts/statuses.csv"})
+
+	assert.Equal(t, map[string]fileState{"fts/login.ft": after, "fts/signup.ft": before}, prev, "only signup.ft, edited by someone else, still differs")
+}
+
+// @ft:349
+func TestWatch_RetriesAFailedPass(t *testing.T) {
+	inTempDir(t)
+	runInit(t)
+	out, _ := startWatch(t, 0)
+	waitForOutput(t, out, "synced 0 files")
+
+	// The file can't be written while a directory is in the way of its
+	// temporary copy
+	require.NoError(t, os.Mkdir("fts/login.ft.tmp", 0o755))
+	require.NoError(t, os.WriteFile("fts/login.ft", []byte("Feature: Login\n  Scenario: User logs in\n    Given a user\n"), 0o644))
+	waitForOutput(t, out, "sync failed: writing fts/login.ft")
+
+	require.NoError(t, os.Remove("fts/login.ft.tmp"))
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile("fts/login.ft")
+		return err == nil && strings.Contains(string(data), "@ft:1")
+	}, 5*time.Second, 10*time.Millisecond, "output so far:\n%s", out)
+	assert.Equal(t, 1, strings.Count(out.String(), "sync failed"), "a repeated failure is printed once")
+	fx := dbtest.Open(t, "fts/ft.db")
+	assert.Equal(t, 1, fx.CountScenarios())
+}
@@ -5,6 +5,7 @@ package dbtest
 
 import (
 	"database/sql"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -52,6 +53,15 @@ func (f *Fixture) SchemaVersion() int {
 	return version
 }
 
+// SetSchemaVersion records version as the applied migration version. The
+// next open re-applies the later migrations and fails, as they're already
+// applied, which shows whether anything opened the database since.
+func (f *Fixture) SetSchemaVersion(version int) {
+	f.t.Helper()
+	_, err := f.sqlDB.Exec(`UPDATE schema_version SET version = ?`, version)
+	require.NoError(f.t, err)
+}
+
 // TableExists reports whether a table with the given name exists.
 func (f *Fixture) TableExists(name string) bool {
 	f.t.Helper()
@@ -117,6 +127,14 @@ func (f *Fixture) FileID(path string) int64 {
 	return id
 }
 
+// FileContent returns the stored header of the file with the given path.
+func (f *Fixture) FileContent(path string) sql.NullString {
+	f.t.Helper()
+	var content sql.NullString
+	require.NoError(f.t, f.sqlDB.QueryRow(`SELECT content FROM files WHERE file_path = ?`, path).Scan(&content))
+	return content
+}
+
 // FileDeleted reports whether the file's deleted flag is set.
 func (f *Fixture) FileDeleted(path string) bool {
 	f.t.Helper()
@@ -173,6 +191,14 @@ func (f *Fixture) ScenarioName(id int64) string {
 	return name
 }
 
+// ScenarioRule returns the (possibly NULL) rule column for a scenario.
+func (f *Fixture) ScenarioRule(id int64) sql.NullString {
+	f.t.Helper()
+	var rule sql.NullString
+	require.NoError(f.t, f.sqlDB.QueryRow(`SELECT rule FROM scenarios WHERE id = ?`, id).Scan(&rule))
+	return rule
+}
+
 // ScenarioByName returns the id and name of the scenario matching name,
 // confirming the record exists.
 func (f *Fixture) ScenarioByName(name string) (id int64, foundName string) {
@@ -283,3 +309,44 @@ func (f *Fixture) TestLink(scenarioID int64) (filePath string, lineNumber int) {
 	).Scan(&filePath, &lineNumber))
 	return filePath, lineNumber
 }
+
+// CountDiagnostics returns the number of diagnostics recorded for a file.
+func (f *Fixture) CountDiagnostics(path string) int {
+	f.t.Helper()
+	var count int
+	require.NoError(f.t, f.sqlDB.QueryRow(`SELECT COUNT(*) FROM diagnostics WHERE file_path = ?`, path).Scan(&count))
+	return count
+}
+
+// Diagnostic returns the first diagnostic recorded for a file.
+func (f *Fixture) Diagnostic(path string) (line, column int, severity, message string) {
+	f.t.Helper()
+	require.NoError(f.t, f.sqlDB.QueryRow(
+		`SELECT line, column, severity, message FROM diagnostics WHERE file_path = ? ORDER BY line, column, id LIMIT 1`, path,
+	).Scan(&line, &column, &severity, &message))
+	return line, column, severity, message
+}
+
+// DiagnosticRange returns the first and last line of the first diagnostic
+// recorded for a file.
+func (f *Fixture) DiagnosticRange(path string) (line, endLine int) {
+	f.t.Helper()
+	require.NoError(f.t, f.sqlDB.QueryRow(
+		`SELECT line, end_line FROM diagnostics WHERE file_path = ? ORDER BY line, column, id LIMIT 1`, path,
+	).Scan(&line, &endLine))
+	return line, endLine
+}
+
+// FailInserts makes every later insert into table fail, e.g. to check that
+// a write error part way through a sync rolls the whole pass back. An empty
+// name fails every insert; otherwise only rows whose name column matches.
+func (f *Fixture) FailInserts(table, name string) {
+	f.t.Helper()
+	when := ""
+	if name != "" {
+		when = "WHEN NEW.name = '" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	_, err := f.sqlDB.Exec(`CREATE TRIGGER fail_` + table + `_insert BEFORE INSERT ON ` + table + ` ` + when + `
+		BEGIN SELECT RAISE(ABORT, 'injected failure'); END`)
+	require.NoError(f.t, err)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyncLockName is the lock file under DataDir that one ft sync at a time
+// holds, so two syncs don't both insert scenarios and write their tags.
+const SyncLockName = "sync.lock"
+
+// WatchLockName is the lock file under DataDir held by a running ft watch
+// for as long as it runs.
+const WatchLockName = "watch.lock"
+
+const statusesLockName = statusesFileName + ".lock"
+
+// statusesLockWait is how long a statuses file rewrite waits for another
+// one to finish. Rewrites take milliseconds, so running out means the
+// holder is stuck.
+const statusesLockWait = 10 * time.Second
+
+// lockPollInterval is how often a waiting AcquireLock retries.
+const lockPollInterval = 50 * time.Millisecond
+
+// ErrLocked is matched by the errors AcquireLock returns when another
+// process holds the lock for longer than the caller would wait.
+var ErrLocked = errors.New("lock held by another process")
+
+// LockedError says which lock was held, and by which process if known.
+type LockedError struct {
+	Path string
+	PID  int // 0 if the holder's pid couldn't be read
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%s is held by another process", e.Path)
+	}
+	return fmt.Sprintf("%s is held by another process (pid %d)", e.Path, e.PID)
+}
+
+func (e *LockedError) Is(target error) bool { return target == ErrLocked }
+
+// Lock is an advisory lock on a file under DataDir. The operating system
+// releases it if its process dies, and the file exists only while the lock
+// is held.
+type Lock struct {
+	f    *os.File
+	path string
+}
+
+// AcquireLock takes the lock named name under DataDir, retrying for up to
+// wait while another process holds it; a wait of 0 tries once. It returns
+// a *LockedError if the lock is still held when the wait runs out.
+func AcquireLock(name string, wait time.Duration) (*Lock, error) {
+	path := filepath.Join(DataDir, name)
+	deadline := time.Now().Add(wait)
+	for {
+		l, err := tryLock(path)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			return l, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, &LockedError{Path: path, PID: LockHolder(name)}
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// tryLock takes the lock at path if it's free, returning nil if it isn't.
+func tryLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	ok, err := lockFile(f)
+	if err != nil || !ok {
+		f.Close()
+		return nil, err
+	}
+
+	// The previous holder removes the file before releasing it, so the
+	// file locked here may no longer be the one at path
+	held, err := f.Stat()
+	if err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, err
+	}
+	current, err := os.Stat(path)
+	if err != nil || !os.SameFile(held, current) {
+		unlockFile(f)
+		f.Close()
+		return nil, nil
+	}
+
+	if err := f.Truncate(0); err == nil {
+		f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	}
+	return &Lock{f: f, path: path}, nil
+}
+
+// LockHolder returns the pid recorded in the lock file named name under
+// DataDir, or 0 if there's no such file or it holds no pid. The file exists
+// while the lock is held, or after its holder died without releasing it.
+func LockHolder(name string) int {
+	data, err := os.ReadFile(filepath.Join(DataDir, name))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// Unlock removes the lock file and releases the lock.
+func (l *Lock) Unlock() error {
+	os.Remove(l.path)
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// withStatusesLock runs fn holding the statuses file lock, so rewrites of
+// the file by concurrent ft processes don't interleave.
+func withStatusesLock(fn func() error) error {
+	l, err := AcquireLock(statusesLockName, statusesLockWait)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+	return fn()
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// @ft:315
+func TestUpsertStatusRow_ConcurrentWritesAreNotLost(t *testing.T) {
+	orig, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(orig) })
+	require.NoError(t, EnsureDataDir())
+	require.NoError(t, EnsureStatusesFile())
+
+	var wg sync.WaitGroup
+	for id := range int64(20) {
+		wg.Go(func() { assert.NoError(t, upsertStatusRow(id+1, "accepted")) })
+	}
+	wg.Wait()
+
+	rows, err := ReadStatusesFile()
+	require.NoError(t, err)
+	assert.Len(t, rows, 20, fmt.Sprint(rows))
+	assert.NoFileExists(t, StatusesPath()+".lock")
+}
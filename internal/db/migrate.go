@@ -36,6 +36,28 @@ var All = []string{
 		updated_at  DATETIME NOT NULL DEFAULT (datetime('now')),
 		UNIQUE(scenario_id, file_path, line_number)
 	)`,
+	`ALTER TABLE scenarios ADD COLUMN rule TEXT`,
+	`CREATE TABLE diagnostics (
+		id         INTEGER PRIMARY KEY,
+		file_path  TEXT NOT NULL,
+		line       INTEGER NOT NULL,
+		column     INTEGER NOT NULL,
+		severity   TEXT NOT NULL,
+		message    TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT (datetime('now'))
+	)`,
+	`ALTER TABLE diagnostics ADD COLUMN end_line INTEGER`,
+	`ALTER TABLE files ADD COLUMN content TEXT`,
+	`ALTER TABLE files ADD COLUMN hash TEXT`,
+	`ALTER TABLE files ADD COLUMN size INTEGER`,
+	`ALTER TABLE files ADD COLUMN mod_time INTEGER`,
+	`CREATE TABLE test_files (
+		file_path TEXT PRIMARY KEY,
+		size      INTEGER NOT NULL,
+		mod_time  INTEGER NOT NULL,
+		tags      TEXT NOT NULL
+	)`,
+	`ALTER TABLE scenarios ADD COLUMN tags TEXT`,
 }
 
 func Migrate(db *sql.DB) error {
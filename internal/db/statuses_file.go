@@ -75,8 +75,29 @@ func ReadStatusesFile() ([]StatusRow, error) {
 
 // WriteStatusesFile overwrites the statuses file with the given rows,
 // preceded by the header row, sorted by id. Used both to create an empty
-// file and to backfill it from the DB's current statuses.
+// file and to backfill it from the DB's current statuses. It holds the
+// statuses lock while writing, so concurrent ft processes don't clobber
+// each other's temporary file.
 func WriteStatusesFile(rows []StatusRow) error {
+	return withStatusesLock(func() error { return writeStatusesFile(rows) })
+}
+
+// WriteCurrentStatusesFile rewrites the statuses file from the database's
+// current statuses, read under the statuses lock and outside any
+// transaction: a status another process recorded since s's transaction
+// committed is written too, rather than overwritten by a stale snapshot.
+// Meant for an AfterCommit callback.
+func (s *Store) WriteCurrentStatusesFile() error {
+	return withStatusesLock(func() error {
+		rows, err := (&Store{db: s.db, q: s.db}).AllCurrentStatuses()
+		if err != nil {
+			return err
+		}
+		return writeStatusesFile(rows)
+	})
+}
+
+func writeStatusesFile(rows []StatusRow) error {
 	sorted := make([]StatusRow, len(rows))
 	copy(sorted, rows)
 	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ScenarioID < sorted[j].ScenarioID })
@@ -112,13 +133,20 @@ func WriteStatusesFile(rows []StatusRow) error {
 
 // upsertStatusRow updates the given scenario's row in the statuses file, or
 // inserts a new one in sorted position if it doesn't have one yet. Creates
-// the file first if it doesn't exist.
+// the file first if it doesn't exist. The read and the rewrite happen under
+// one hold of the statuses lock, so a concurrent rewrite can't be lost.
 func upsertStatusRow(id int64, status string) error {
-	rows, err := ReadStatusesFile()
-	if err != nil {
-		return err
-	}
+	return withStatusesLock(func() error {
+		rows, err := ReadStatusesFile()
+		if err != nil {
+			return err
+		}
+		return writeStatusesFile(upsertedRows(rows, id, status))
+	})
+}
 
+// upsertedRows sets id's status in rows, appending a row if it has none.
+func upsertedRows(rows []StatusRow, id int64, status string) []StatusRow {
 	found := false
 	for i, r := range rows {
 		if r.ScenarioID == id {
@@ -130,6 +158,5 @@ func upsertStatusRow(id int64, status string) error {
 	if !found {
 		rows = append(rows, StatusRow{ScenarioID: id, Status: status})
 	}
-
-	return WriteStatusesFile(rows)
+	return rows
 }
@@ -2,6 +2,8 @@ package db
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -17,14 +19,26 @@ func Now() string {
 }
 
 // Store wraps a *sql.DB and exposes the application's data-access operations,
-// keeping raw SQL out of the business logic in cmd.
+// keeping raw SQL out of the business logic in cmd. A Store obtained from
+// WithTx runs every operation inside that transaction.
 type Store struct {
 	db *sql.DB
+	tx *sql.Tx
+	q  querier // tx when in a transaction, otherwise db
+
+	afterCommit []func() error
+}
+
+// querier is the query API shared by *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
 // NewStore wraps an existing *sql.DB in a Store.
 func NewStore(sqlDB *sql.DB) *Store {
-	return &Store{db: sqlDB}
+	return &Store{db: sqlDB, q: sqlDB}
 }
 
 // OpenStore opens the database at path and returns it wrapped in a Store.
@@ -41,9 +55,71 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
+// WithTx runs fn with a Store whose operations all happen in one database
+// transaction. The transaction commits if fn returns nil and rolls back
+// otherwise, so either every change fn made is kept or none is. Functions
+// registered with AfterCommit run once the commit has succeeded.
+func (s *Store) WithTx(fn func(tx *Store) error) error {
+	if s.tx != nil {
+		return errors.New("already in a transaction")
+	}
+	sqlTx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	txStore := &Store{db: s.db, tx: sqlTx, q: sqlTx}
+
+	if err := fn(txStore); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rolling back: %w", rbErr))
+		}
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	var errs []error
+	for _, f := range txStore.afterCommit {
+		if err := f(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AfterCommit defers f, typically a file write that must only happen once
+// the database changes it reflects are durable, until the transaction
+// commits. Outside a transaction f runs immediately.
+func (s *Store) AfterCommit(f func() error) error {
+	if s.tx == nil {
+		return f()
+	}
+	s.afterCommit = append(s.afterCommit, f)
+	return nil
+}
+
+// inTx runs fn in the Store's transaction, or in a new one of its own when
+// the Store isn't in a transaction.
+func (s *Store) inTx(fn func(q querier) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	sqlTx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(sqlTx); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
 type ScenarioListRow struct {
 	ID       int64
 	FilePath string
+	Rule     string
 	Name     string
 	Status   string
 }
@@ -51,8 +127,11 @@ type ScenarioListRow struct {
 type ScenarioDetail struct {
 	ID       int64
 	Name     string
+	Rule     string
+	FileID   int64
 	FilePath string
 	Content  sql.NullString
+	Tags     string // tags other than @ft, space-separated
 }
 
 type StatusEntry struct {
@@ -73,7 +152,9 @@ type TestLink struct {
 type ScenarioRecord struct {
 	ID      int64
 	Name    string
+	Rule    string
 	Content sql.NullString
+	Tags    string // tags other than @ft, space-separated
 }
 
 type FileRecord struct {
@@ -87,17 +168,36 @@ type TestLinkRecord struct {
 	LineNumber int
 }
 
+// OrphanedTestLink is a test still tagged with a scenario that has been
+// removed from its .ft file.
+type OrphanedTestLink struct {
+	ScenarioID int64
+	Name       string
+	FilePath   string
+	LineNumber int
+}
+
+// Diagnostic is a problem found in a .ft file, e.g. a parse error.
+type Diagnostic struct {
+	FilePath string
+	Line     int
+	EndLine  int // last line of the problem's region; equals Line for one line
+	Column   int
+	Severity string // "error" or "warning"
+	Message  string
+}
+
 // IsTested reports whether a scenario has any linked tests.
 func (s *Store) IsTested(scenarioID int64) bool {
 	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM test_links WHERE scenario_id = ?`, scenarioID).Scan(&count)
+	err := s.q.QueryRow(`SELECT COUNT(*) FROM test_links WHERE scenario_id = ?`, scenarioID).Scan(&count)
 	return err == nil && count > 0
 }
 
 // ListScenarios returns all scenarios joined with their file path and current status.
 func (s *Store) ListScenarios() ([]ScenarioListRow, error) {
-	rows, err := s.db.Query(`
-		SELECT s.id, f.file_path, s.name,
+	rows, err := s.q.Query(`
+		SELECT s.id, f.file_path, COALESCE(s.rule, ''), s.name,
 			COALESCE(
 				(SELECT status FROM statuses WHERE scenario_id = s.id ORDER BY changed_at DESC, id DESC LIMIT 1),
 				'no-activity'
@@ -114,7 +214,7 @@ func (s *Store) ListScenarios() ([]ScenarioListRow, error) {
 	var results []ScenarioListRow
 	for rows.Next() {
 		var r ScenarioListRow
-		if err := rows.Scan(&r.ID, &r.FilePath, &r.Name, &r.Status); err != nil {
+		if err := rows.Scan(&r.ID, &r.FilePath, &r.Rule, &r.Name, &r.Status); err != nil {
 			return nil, err
 		}
 		results = append(results, r)
@@ -125,39 +225,39 @@ func (s *Store) ListScenarios() ([]ScenarioListRow, error) {
 // ScenarioDetail fetches a scenario's core fields along with its file path.
 func (s *Store) ScenarioDetail(id int64) (ScenarioDetail, error) {
 	var d ScenarioDetail
-	err := s.db.QueryRow(`
-		SELECT s.id, s.name, f.file_path, s.content
+	err := s.q.QueryRow(`
+		SELECT s.id, s.name, COALESCE(s.rule, ''), f.id, f.file_path, s.content, COALESCE(s.tags, '')
 		FROM scenarios s
 		JOIN files f ON s.file_id = f.id
 		WHERE s.id = ?
-	`, id).Scan(&d.ID, &d.Name, &d.FilePath, &d.Content)
+	`, id).Scan(&d.ID, &d.Name, &d.Rule, &d.FileID, &d.FilePath, &d.Content, &d.Tags)
 	return d, err
 }
 
 // ScenarioBasic fetches a scenario's id, name, and created_at.
 func (s *Store) ScenarioBasic(id int64) (name string, createdAt time.Time, err error) {
-	err = s.db.QueryRow(`SELECT name, created_at FROM scenarios WHERE id = ?`, id).Scan(&name, &createdAt)
+	err = s.q.QueryRow(`SELECT name, created_at FROM scenarios WHERE id = ?`, id).Scan(&name, &createdAt)
 	return
 }
 
 // ScenarioExists reports whether a scenario with the given ID exists.
 func (s *Store) ScenarioExists(id int64) bool {
 	var existingID int64
-	err := s.db.QueryRow(`SELECT id FROM scenarios WHERE id = ?`, id).Scan(&existingID)
+	err := s.q.QueryRow(`SELECT id FROM scenarios WHERE id = ?`, id).Scan(&existingID)
 	return err == nil
 }
 
 // CurrentStatus returns the most recently changed status for a scenario.
 func (s *Store) CurrentStatus(scenarioID int64) (string, error) {
 	var status string
-	err := s.db.QueryRow(`SELECT status FROM statuses WHERE scenario_id = ? ORDER BY changed_at DESC, id DESC LIMIT 1`, scenarioID).Scan(&status)
+	err := s.q.QueryRow(`SELECT status FROM statuses WHERE scenario_id = ? ORDER BY changed_at DESC, id DESC LIMIT 1`, scenarioID).Scan(&status)
 	return status, err
 }
 
 // LatestInsertedStatus returns the most recently inserted status for a scenario.
 func (s *Store) LatestInsertedStatus(scenarioID int64) (string, error) {
 	var status string
-	err := s.db.QueryRow(`SELECT status FROM statuses WHERE scenario_id = ? ORDER BY id DESC LIMIT 1`, scenarioID).Scan(&status)
+	err := s.q.QueryRow(`SELECT status FROM statuses WHERE scenario_id = ? ORDER BY id DESC LIMIT 1`, scenarioID).Scan(&status)
 	return status, err
 }
 
@@ -170,7 +270,7 @@ func (s *Store) LatestInsertedStatusIsRemoved(scenarioID int64) bool {
 // HasStatusHistory reports whether a scenario has any status entries.
 func (s *Store) HasStatusHistory(scenarioID int64) bool {
 	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM statuses WHERE scenario_id = ?`, scenarioID).Scan(&count)
+	err := s.q.QueryRow(`SELECT COUNT(*) FROM statuses WHERE scenario_id = ?`, scenarioID).Scan(&count)
 	return err == nil && count > 0
 }
 
@@ -181,21 +281,21 @@ func (s *Store) InsertStatus(scenarioID int64, status string) error {
 	if err := s.InsertStatusAt(scenarioID, status, changedAt); err != nil {
 		return err
 	}
-	return upsertStatusRow(scenarioID, status)
+	return s.AfterCommit(func() error { return upsertStatusRow(scenarioID, status) })
 }
 
 // InsertStatusAt records a status with an explicit changed_at, without
 // touching the statuses file. Used when replaying the statuses file itself
 // during a rebuild, so replay doesn't rewrite what it just read.
 func (s *Store) InsertStatusAt(scenarioID int64, status, changedAt string) error {
-	_, err := s.db.Exec(`INSERT INTO statuses (scenario_id, status, changed_at) VALUES (?, ?, ?)`, scenarioID, status, changedAt)
+	_, err := s.q.Exec(`INSERT INTO statuses (scenario_id, status, changed_at) VALUES (?, ?, ?)`, scenarioID, status, changedAt)
 	return err
 }
 
 // CountStatuses returns the total number of status records in the database.
 func (s *Store) CountStatuses() (int, error) {
 	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM statuses`).Scan(&count)
+	err := s.q.QueryRow(`SELECT COUNT(*) FROM statuses`).Scan(&count)
 	return count, err
 }
 
@@ -205,7 +305,7 @@ func (s *Store) CountStatuses() (int, error) {
 // already has status history in the DB (e.g. an existing project adopting
 // this feature).
 func (s *Store) AllCurrentStatuses() ([]StatusRow, error) {
-	rows, err := s.db.Query(`
+	rows, err := s.q.Query(`
 		SELECT s.id,
 			(SELECT status FROM statuses WHERE scenario_id = s.id ORDER BY changed_at DESC, id DESC LIMIT 1) AS current_status
 		FROM scenarios s
@@ -227,9 +327,37 @@ func (s *Store) AllCurrentStatuses() ([]StatusRow, error) {
 	return result, rows.Err()
 }
 
+// LastStatusID returns the ID of the most recently inserted status, or 0 if
+// there are none.
+func (s *Store) LastStatusID() (int64, error) {
+	var id int64
+	err := s.q.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM statuses`).Scan(&id)
+	return id, err
+}
+
+// StatusesAfter returns the statuses inserted after the status with the
+// given ID, in insertion order.
+func (s *Store) StatusesAfter(id int64) ([]StatusRow, error) {
+	rows, err := s.q.Query(`SELECT scenario_id, status FROM statuses WHERE id > ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []StatusRow
+	for rows.Next() {
+		var r StatusRow
+		if err := rows.Scan(&r.ScenarioID, &r.Status); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
 // StatusHistory returns all status entries for a scenario, most recent first.
 func (s *Store) StatusHistory(scenarioID int64) ([]StatusEntry, error) {
-	rows, err := s.db.Query(`SELECT status, changed_at FROM statuses WHERE scenario_id = ? ORDER BY changed_at DESC, id DESC`, scenarioID)
+	rows, err := s.q.Query(`SELECT status, changed_at FROM statuses WHERE scenario_id = ? ORDER BY changed_at DESC, id DESC`, scenarioID)
 	if err != nil {
 		return nil, err
 	}
@@ -248,7 +376,7 @@ func (s *Store) StatusHistory(scenarioID int64) ([]StatusEntry, error) {
 
 // TestLinks returns the test links for a scenario.
 func (s *Store) TestLinks(scenarioID int64) ([]TestLink, error) {
-	rows, err := s.db.Query(`SELECT file_path, line_number FROM test_links WHERE scenario_id = ? ORDER BY file_path, line_number`, scenarioID)
+	rows, err := s.q.Query(`SELECT file_path, line_number FROM test_links WHERE scenario_id = ? ORDER BY file_path, line_number`, scenarioID)
 	if err != nil {
 		return nil, err
 	}
@@ -268,13 +396,13 @@ func (s *Store) TestLinks(scenarioID int64) ([]TestLink, error) {
 // CountScenarios returns the total number of scenarios.
 func (s *Store) CountScenarios() (int, error) {
 	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM scenarios`).Scan(&count)
+	err := s.q.QueryRow(`SELECT COUNT(*) FROM scenarios`).Scan(&count)
 	return count, err
 }
 
 // StatusCounts returns the count of scenarios grouped by current status.
 func (s *Store) StatusCounts() ([]StatusCount, error) {
-	rows, err := s.db.Query(`
+	rows, err := s.q.Query(`
 		SELECT COALESCE(
 			(SELECT status FROM statuses WHERE scenario_id = s.id ORDER BY changed_at DESC, id DESC LIMIT 1),
 			'no-activity'
@@ -299,9 +427,9 @@ func (s *Store) StatusCounts() ([]StatusCount, error) {
 	return counts, rows.Err()
 }
 
-// ScenariosByFile returns the scenarios (id, name, content) belonging to a file.
+// ScenariosByFile returns the scenarios (id, name, rule, content, tags) belonging to a file.
 func (s *Store) ScenariosByFile(fileID int64) (map[int64]ScenarioRecord, error) {
-	rows, err := s.db.Query(`SELECT id, name, content FROM scenarios WHERE file_id = ?`, fileID)
+	rows, err := s.q.Query(`SELECT id, name, COALESCE(rule, ''), content, COALESCE(tags, '') FROM scenarios WHERE file_id = ?`, fileID)
 	if err != nil {
 		return nil, err
 	}
@@ -310,7 +438,7 @@ func (s *Store) ScenariosByFile(fileID int64) (map[int64]ScenarioRecord, error)
 	result := make(map[int64]ScenarioRecord)
 	for rows.Next() {
 		var r ScenarioRecord
-		if err := rows.Scan(&r.ID, &r.Name, &r.Content); err != nil {
+		if err := rows.Scan(&r.ID, &r.Name, &r.Rule, &r.Content, &r.Tags); err != nil {
 			return nil, err
 		}
 		result[r.ID] = r
@@ -319,18 +447,35 @@ func (s *Store) ScenariosByFile(fileID int64) (map[int64]ScenarioRecord, error)
 }
 
 // UpdateScenarioNameContent updates a scenario's name and content.
-func (s *Store) UpdateScenarioNameContent(id int64, name, content string) {
-	s.db.Exec(`UPDATE scenarios SET name = ?, content = ?, updated_at = datetime('now') WHERE id = ?`, name, content, id)
+func (s *Store) UpdateScenarioNameContent(id int64, name, content string) error {
+	_, err := s.q.Exec(`UPDATE scenarios SET name = ?, content = ?, updated_at = datetime('now') WHERE id = ?`, name, content, id)
+	return err
 }
 
 // UpdateScenarioContent updates a scenario's content only.
-func (s *Store) UpdateScenarioContent(id int64, content string) {
-	s.db.Exec(`UPDATE scenarios SET content = ?, updated_at = datetime('now') WHERE id = ?`, content, id)
+func (s *Store) UpdateScenarioContent(id int64, content string) error {
+	_, err := s.q.Exec(`UPDATE scenarios SET content = ?, updated_at = datetime('now') WHERE id = ?`, content, id)
+	return err
+}
+
+// UpdateScenarioRule updates the name of the Rule: a scenario belongs to.
+// An empty rule is stored as NULL.
+func (s *Store) UpdateScenarioRule(id int64, rule string) error {
+	_, err := s.q.Exec(`UPDATE scenarios SET rule = ?, updated_at = datetime('now') WHERE id = ?`, nullIfEmpty(rule), id)
+	return err
 }
 
-// InsertScenario creates a new scenario and returns its ID.
-func (s *Store) InsertScenario(fileID int64, name, content string) (int64, error) {
-	result, err := s.db.Exec(`INSERT INTO scenarios (file_id, name, content) VALUES (?, ?, ?)`, fileID, name, content)
+// UpdateScenarioTags records a scenario's tags other than @ft, leaving the
+// row untouched if they haven't changed. No tags are stored as NULL.
+func (s *Store) UpdateScenarioTags(id int64, tags string) error {
+	_, err := s.q.Exec(`UPDATE scenarios SET tags = ? WHERE id = ? AND tags IS NOT ?`, nullIfEmpty(tags), id, nullIfEmpty(tags))
+	return err
+}
+
+// InsertScenario creates a new scenario and returns its ID. An empty rule is
+// stored as NULL.
+func (s *Store) InsertScenario(fileID int64, name, rule, content string) (int64, error) {
+	result, err := s.q.Exec(`INSERT INTO scenarios (file_id, name, rule, content) VALUES (?, ?, ?, ?)`, fileID, name, nullIfEmpty(rule), content)
 	if err != nil {
 		return 0, err
 	}
@@ -341,20 +486,32 @@ func (s *Store) InsertScenario(fileID int64, name, content string) (int64, error
 // from an existing @ft:<id> tag on a scenario in a file not yet tracked in
 // the DB (e.g. after a rebuild). Callers must confirm via ScenarioExists that
 // the id isn't already claimed before calling this.
-func (s *Store) InsertScenarioWithID(id, fileID int64, name, content string) error {
-	_, err := s.db.Exec(`INSERT INTO scenarios (id, file_id, name, content) VALUES (?, ?, ?, ?)`, id, fileID, name, content)
+func (s *Store) InsertScenarioWithID(id, fileID int64, name, rule, content string) error {
+	_, err := s.q.Exec(`INSERT INTO scenarios (id, file_id, name, rule, content) VALUES (?, ?, ?, ?, ?)`, id, fileID, name, nullIfEmpty(rule), content)
+	return err
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// MoveScenario reassigns a scenario to another file, keeping its ID and
+// history.
+func (s *Store) MoveScenario(id, fileID int64) error {
+	_, err := s.q.Exec(`UPDATE scenarios SET file_id = ?, updated_at = datetime('now') WHERE id = ?`, fileID, id)
 	return err
 }
 
 // DeleteScenario removes a scenario by ID.
-func (s *Store) DeleteScenario(id int64) {
-	s.db.Exec(`DELETE FROM scenarios WHERE id = ?`, id)
+func (s *Store) DeleteScenario(id int64) error {
+	_, err := s.q.Exec(`DELETE FROM scenarios WHERE id = ?`, id)
+	return err
 }
 
 // FindActiveFileID looks up the ID of a non-deleted file by path.
 func (s *Store) FindActiveFileID(path string) (int64, bool, error) {
 	var fileID int64
-	err := s.db.QueryRow(`SELECT id FROM files WHERE file_path = ? AND deleted = FALSE`, path).Scan(&fileID)
+	err := s.q.QueryRow(`SELECT id FROM files WHERE file_path = ? AND deleted = FALSE`, path).Scan(&fileID)
 	if err == sql.ErrNoRows {
 		return 0, false, nil
 	}
@@ -367,7 +524,7 @@ func (s *Store) FindActiveFileID(path string) (int64, bool, error) {
 // FindDeletedFileID looks up the ID of a deleted file by path.
 func (s *Store) FindDeletedFileID(path string) (int64, bool, error) {
 	var fileID int64
-	err := s.db.QueryRow(`SELECT id FROM files WHERE file_path = ? AND deleted = TRUE`, path).Scan(&fileID)
+	err := s.q.QueryRow(`SELECT id FROM files WHERE file_path = ? AND deleted = TRUE`, path).Scan(&fileID)
 	if err == sql.ErrNoRows {
 		return 0, false, nil
 	}
@@ -378,13 +535,84 @@ func (s *Store) FindDeletedFileID(path string) (int64, bool, error) {
 }
 
 // UndeleteFile clears the deleted flag on a file.
-func (s *Store) UndeleteFile(id int64) {
-	s.db.Exec(`UPDATE files SET deleted = FALSE, updated_at = datetime('now') WHERE id = ?`, id)
+func (s *Store) UndeleteFile(id int64) error {
+	_, err := s.q.Exec(`UPDATE files SET deleted = FALSE, updated_at = datetime('now') WHERE id = ?`, id)
+	return err
+}
+
+// RenameFile changes the path of a file record in place, keeping its ID and
+// therefore its scenarios.
+func (s *Store) RenameFile(id int64, path string) error {
+	_, err := s.q.Exec(`UPDATE files SET file_path = ?, updated_at = datetime('now') WHERE id = ?`, path, id)
+	return err
+}
+
+// FileContent returns a file's stored header: its Feature: line,
+// description and Background, as last synced. Invalid if never stored.
+func (s *Store) FileContent(id int64) (sql.NullString, error) {
+	var content sql.NullString
+	err := s.q.QueryRow(`SELECT content FROM files WHERE id = ?`, id).Scan(&content)
+	return content, err
+}
+
+// UpdateFileContent stores a file's header, leaving the record untouched if
+// it hasn't changed.
+func (s *Store) UpdateFileContent(id int64, content string) error {
+	_, err := s.q.Exec(`UPDATE files SET content = ?, updated_at = datetime('now') WHERE id = ? AND content IS NOT ?`, content, id, content)
+	return err
+}
+
+// FileStamp is what an active file looked like when it was last synced.
+type FileStamp struct {
+	ID             int64
+	Hash           string
+	Size           int64
+	ModTime        int64 // Unix nanoseconds, or 0 if sync rewrote the file
+	HasDiagnostics bool
+}
+
+// FileStamps returns the stamps of the active files that have one, by path.
+func (s *Store) FileStamps() (map[string]FileStamp, error) {
+	rows, err := s.q.Query(`
+		SELECT f.id, f.file_path, f.hash, COALESCE(f.size, 0), COALESCE(f.mod_time, 0),
+			EXISTS (SELECT 1 FROM diagnostics d WHERE d.file_path = f.file_path)
+		FROM files f
+		WHERE f.deleted = FALSE AND f.hash IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stamps := make(map[string]FileStamp)
+	for rows.Next() {
+		var path string
+		var st FileStamp
+		if err := rows.Scan(&st.ID, &path, &st.Hash, &st.Size, &st.ModTime, &st.HasDiagnostics); err != nil {
+			return nil, err
+		}
+		stamps[path] = st
+	}
+	return stamps, rows.Err()
+}
+
+// UpdateFileStamp records a file's content hash, size and modification time
+// as of this sync.
+func (s *Store) UpdateFileStamp(id int64, hash string, size, modTime int64) error {
+	_, err := s.q.Exec(`UPDATE files SET hash = ?, size = ?, mod_time = ? WHERE id = ?`, hash, size, modTime, id)
+	return err
+}
+
+// ClearFileStamp forgets a file's stamp, so the next sync reads it whatever
+// it looks like.
+func (s *Store) ClearFileStamp(id int64) error {
+	_, err := s.q.Exec(`UPDATE files SET hash = NULL, size = NULL, mod_time = NULL WHERE id = ?`, id)
+	return err
 }
 
 // InsertFile creates a new file record and returns its ID.
 func (s *Store) InsertFile(path string) (int64, error) {
-	result, err := s.db.Exec(`INSERT INTO files (file_path) VALUES (?)`, path)
+	result, err := s.q.Exec(`INSERT INTO files (file_path) VALUES (?)`, path)
 	if err != nil {
 		return 0, err
 	}
@@ -393,7 +621,7 @@ func (s *Store) InsertFile(path string) (int64, error) {
 
 // ActiveFiles returns all non-deleted files.
 func (s *Store) ActiveFiles() ([]FileRecord, error) {
-	rows, err := s.db.Query(`SELECT id, file_path FROM files WHERE deleted = FALSE`)
+	rows, err := s.q.Query(`SELECT id, file_path FROM files WHERE deleted = FALSE`)
 	if err != nil {
 		return nil, err
 	}
@@ -411,14 +639,15 @@ func (s *Store) ActiveFiles() ([]FileRecord, error) {
 }
 
 // MarkFileDeleted flags a file as deleted.
-func (s *Store) MarkFileDeleted(id int64) {
-	s.db.Exec(`UPDATE files SET deleted = TRUE, updated_at = datetime('now') WHERE id = ?`, id)
+func (s *Store) MarkFileDeleted(id int64) error {
+	_, err := s.q.Exec(`UPDATE files SET deleted = TRUE, updated_at = datetime('now') WHERE id = ?`, id)
+	return err
 }
 
 // AllScenarioIDs returns the set of every scenario ID currently in the database.
 func (s *Store) AllScenarioIDs() (map[int64]bool, error) {
 	validIDs := make(map[int64]bool)
-	rows, err := s.db.Query(`SELECT id FROM scenarios`)
+	rows, err := s.q.Query(`SELECT id FROM scenarios`)
 	if err != nil {
 		return nil, err
 	}
@@ -433,28 +662,165 @@ func (s *Store) AllScenarioIDs() (map[int64]bool, error) {
 	return validIDs, rows.Err()
 }
 
+// AllTestLinks returns every test link, ordered by file and line.
+func (s *Store) AllTestLinks() ([]TestLinkRecord, error) {
+	rows, err := s.q.Query(`SELECT scenario_id, file_path, line_number FROM test_links ORDER BY file_path, line_number, scenario_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []TestLinkRecord
+	for rows.Next() {
+		var l TestLinkRecord
+		if err := rows.Scan(&l.ScenarioID, &l.FilePath, &l.LineNumber); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// OrphanedTestLinks returns the test links of scenarios whose current
+// status is "removed", ordered by test file and line.
+func (s *Store) OrphanedTestLinks() ([]OrphanedTestLink, error) {
+	rows, err := s.q.Query(`
+		SELECT t.scenario_id, s.name, t.file_path, t.line_number
+		FROM test_links t
+		JOIN scenarios s ON s.id = t.scenario_id
+		WHERE (SELECT status FROM statuses WHERE scenario_id = s.id ORDER BY id DESC LIMIT 1) = 'removed'
+		ORDER BY t.file_path, t.line_number
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []OrphanedTestLink
+	for rows.Next() {
+		var l OrphanedTestLink
+		if err := rows.Scan(&l.ScenarioID, &l.Name, &l.FilePath, &l.LineNumber); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
 // ReplaceTestLinks atomically replaces the full contents of the test_links table.
 func (s *Store) ReplaceTestLinks(links []TestLinkRecord) error {
-	tx, err := s.db.Begin()
+	return s.inTx(func(q querier) error {
+		if _, err := q.Exec(`DELETE FROM test_links`); err != nil {
+			return err
+		}
+		for _, l := range links {
+			if _, err := q.Exec(
+				`INSERT INTO test_links (scenario_id, file_path, line_number) VALUES (?, ?, ?)`,
+				l.ScenarioID, l.FilePath, l.LineNumber,
+			); err != nil {
+				return fmt.Errorf("linking %s:%d to @ft:%d: %w", l.FilePath, l.LineNumber, l.ScenarioID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// TestFile is a cached scan of a _test.go file: its size and modification
+// time when scanned and the @ft tags found above its tests, as
+// space-separated line:id pairs.
+type TestFile struct {
+	Size    int64
+	ModTime int64
+	Tags    string
+}
+
+// TestFiles returns the cached scans of test files, by path.
+func (s *Store) TestFiles() (map[string]TestFile, error) {
+	rows, err := s.q.Query(`SELECT file_path, size, mod_time, tags FROM test_files`)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	if _, err := tx.Exec(`DELETE FROM test_links`); err != nil {
-		tx.Rollback()
-		return err
+	files := make(map[string]TestFile)
+	for rows.Next() {
+		var path string
+		var f TestFile
+		if err := rows.Scan(&path, &f.Size, &f.ModTime, &f.Tags); err != nil {
+			return nil, err
+		}
+		files[path] = f
 	}
+	return files, rows.Err()
+}
+
+// SaveTestFile caches the scan of the test file at path.
+func (s *Store) SaveTestFile(path string, f TestFile) error {
+	_, err := s.q.Exec(
+		`INSERT OR REPLACE INTO test_files (file_path, size, mod_time, tags) VALUES (?, ?, ?, ?)`,
+		path, f.Size, f.ModTime, f.Tags,
+	)
+	return err
+}
+
+// DeleteTestFile drops the cached scan of a test file that's gone.
+func (s *Store) DeleteTestFile(path string) error {
+	_, err := s.q.Exec(`DELETE FROM test_files WHERE file_path = ?`, path)
+	return err
+}
+
+// ReplaceDiagnostics replaces every diagnostic recorded for filePath with
+// diags. An empty diags clears the file's diagnostics.
+func (s *Store) ReplaceDiagnostics(filePath string, diags []Diagnostic) error {
+	return s.inTx(func(q querier) error {
+		if _, err := q.Exec(`DELETE FROM diagnostics WHERE file_path = ?`, filePath); err != nil {
+			return err
+		}
+		for _, d := range diags {
+			if _, err := q.Exec(
+				`INSERT INTO diagnostics (file_path, line, end_line, column, severity, message) VALUES (?, ?, ?, ?, ?, ?)`,
+				filePath, d.Line, max(d.EndLine, d.Line), d.Column, d.Severity, d.Message,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-	stmt, err := tx.Prepare(`INSERT INTO test_links (scenario_id, file_path, line_number) VALUES (?, ?, ?)`)
+// Diagnostics returns every recorded diagnostic ordered by file and position.
+func (s *Store) Diagnostics() ([]Diagnostic, error) {
+	rows, err := s.q.Query(`SELECT file_path, line, COALESCE(end_line, line), column, severity, message FROM diagnostics ORDER BY file_path, line, column, id`)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return nil, err
 	}
-	defer stmt.Close()
+	defer rows.Close()
 
-	for _, l := range links {
-		stmt.Exec(l.ScenarioID, l.FilePath, l.LineNumber)
+	var diags []Diagnostic
+	for rows.Next() {
+		var d Diagnostic
+		if err := rows.Scan(&d.FilePath, &d.Line, &d.EndLine, &d.Column, &d.Severity, &d.Message); err != nil {
+			return nil, err
+		}
+		diags = append(diags, d)
 	}
+	return diags, rows.Err()
+}
 
-	return tx.Commit()
+// DeleteDiagnosticsExcept removes the diagnostics of every file not in keep,
+// e.g. files that no longer exist on disk.
+func (s *Store) DeleteDiagnosticsExcept(keep map[string]bool) error {
+	diags, err := s.Diagnostics()
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		if keep[d.FilePath] {
+			continue
+		}
+		if _, err := s.q.Exec(`DELETE FROM diagnostics WHERE file_path = ?`, d.FilePath); err != nil {
+			return err
+		}
+	}
+	return nil
 }
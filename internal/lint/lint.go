@@ -0,0 +1,135 @@
+// Package lint checks parsed .ft files against a set of scenario quality
+// rules. Each Rule inspects one file at a time; new rules are added by
+// appending to Rules.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chriserin/ft/internal/parser"
+)
+
+// Issue is one rule violation.
+type Issue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// File is a .ft file prepared for linting: its AST, parse errors and
+// lossless CST, plus the keyword table of its language.
+type File struct {
+	Path     string
+	Doc      *parser.Document
+	Errors   []parser.ParseError
+	CST      *parser.CST
+	Language *parser.Language
+}
+
+// NewFile parses content for linting.
+func NewFile(path string, content []byte) *File {
+	doc, errs := parser.Parse(path, content)
+	return &File{
+		Path:     path,
+		Doc:      doc,
+		Errors:   errs,
+		CST:      parser.ParseCST(content),
+		Language: parser.LanguageFor(doc.Language),
+	}
+}
+
+// Rule is a named check. Check returns the violations found in f; the
+// File field of each Issue is filled in by Run.
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(f *File) []Issue
+}
+
+// Rules is every known rule, in the order they're listed and run. All are
+// enabled unless configured otherwise.
+var Rules = []Rule{
+	{"syntax", "the file must parse without errors", checkSyntax},
+	{"duplicate-scenario-name", "scenario names must be unique within a file", checkDuplicateNames},
+	{"no-steps", "every scenario must have at least one step", checkNoSteps},
+	{"no-then", "every scenario must have a Then step", checkNoThen},
+	{"steps-outside-scenario", "runs of steps must belong to a Background or scenario", checkStepsOutsideScenario},
+	{"multiple-ft-tags", "a scenario must have at most one @ft tag", checkMultipleFtTags},
+	{"stray-error-comment", "`# ft error:` comments must be removed once the error is fixed", checkStrayErrorComments},
+}
+
+// Lookup returns the rule with the given name.
+func Lookup(name string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Config says which rules are enabled. Rules not mentioned are enabled.
+type Config map[string]bool
+
+// Enabled reports whether the named rule should run.
+func (c Config) Enabled(name string) bool {
+	enabled, ok := c[name]
+	return !ok || enabled
+}
+
+// ParseConfig reads a lint config: one `<rule> = on|off` per line, with
+// blank lines and # comments ignored.
+func ParseConfig(content string) (Config, error) {
+	cfg := Config{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected <rule> = on|off", i+1)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if _, known := Lookup(name); !known {
+			return nil, fmt.Errorf("line %d: unknown rule %q", i+1, name)
+		}
+		switch value {
+		case "on":
+			cfg[name] = true
+		case "off":
+			cfg[name] = false
+		default:
+			return nil, fmt.Errorf("line %d: %s must be on or off, got %q", i+1, name, value)
+		}
+	}
+	return cfg, nil
+}
+
+// Run checks every file against every enabled rule. Issues are sorted by
+// file, then line, then rule.
+func Run(files []*File, cfg Config) []Issue {
+	var issues []Issue
+	for _, f := range files {
+		for _, r := range Rules {
+			if !cfg.Enabled(r.Name) {
+				continue
+			}
+			for _, is := range r.Check(f) {
+				is.File = f.Path
+				is.Rule = r.Name
+				issues = append(issues, is)
+			}
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
@@ -0,0 +1,165 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintContent(t *testing.T, content string, cfg Config) []Issue {
+	t.Helper()
+	return Run([]*File{NewFile("fts/login.ft", []byte(content))}, cfg)
+}
+
+func rulesOf(issues []Issue) []string {
+	var names []string
+	for _, is := range issues {
+		names = append(names, is.Rule)
+	}
+	return names
+}
+
+func TestLint_CleanFileHasNoIssues(t *testing.T) {
+	issues := lintContent(t, `Feature: Login
+  Users log in.
+  Those who forget a password reset it.
+
+  Background:
+    Given a user
+
+  @ft:1
+  Scenario: User logs in
+    When  they log in
+    Then  they see the dashboard
+`, nil)
+	assert.Empty(t, issues)
+}
+
+func TestLint_Syntax(t *testing.T) {
+	issues := lintContent(t, "Feature: Login\n  Examples: Orphaned\n    | a |\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, Issue{File: "fts/login.ft", Line: 2, Rule: "syntax", Message: "Examples must belong to a Scenario Outline"}, issues[0])
+}
+
+func TestLint_DuplicateScenarioName(t *testing.T) {
+	issues := lintContent(t, `Feature: Login
+  Scenario: User logs in
+    Then ok
+
+  Rule: Admins
+    Scenario: User logs in
+      Then ok
+`, nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "duplicate-scenario-name", issues[0].Rule)
+	assert.Equal(t, 6, issues[0].Line)
+	assert.Equal(t, `scenario "User logs in" is already defined on line 2`, issues[0].Message)
+}
+
+func TestLint_NoSteps(t *testing.T) {
+	issues := lintContent(t, "Feature: Login\n  Scenario: Empty\n    Just a description.\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "no-steps", issues[0].Rule)
+	assert.Equal(t, 2, issues[0].Line)
+}
+
+func TestLint_NoThen(t *testing.T) {
+	issues := lintContent(t, "Feature: Login\n  Scenario: Unverified\n    Given a user\n    When  they log in\n    And   wait\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "no-then", issues[0].Rule)
+	assert.Equal(t, `scenario "Unverified" has no Then step`, issues[0].Message)
+}
+
+func TestLint_NoThenUsesFileLanguage(t *testing.T) {
+	issues := lintContent(t, "# language: de\nFunktionalität: Anmeldung\n  Szenario: A\n    Wenn er sich anmeldet\n    Dann sieht er das Dashboard\n", nil)
+	assert.Empty(t, issues)
+
+	issues = lintContent(t, "# language: de\nFunktionalität: Anmeldung\n  Szenario: A\n    Wenn er sich anmeldet\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, `scenario "A" has no Dann step`, issues[0].Message)
+}
+
+func TestLint_StepsOutsideScenario(t *testing.T) {
+	issues := lintContent(t, `Feature: Login
+  Given a user
+  When  they log in
+
+  Rule: Admins
+    Then they see the admin page
+    And  they see users
+
+    Scenario: Admin logs in
+      Then ok
+`, nil)
+	assert.Equal(t, []string{"steps-outside-scenario", "steps-outside-scenario", "steps-outside-scenario", "steps-outside-scenario"}, rulesOf(issues))
+	assert.Equal(t, 2, issues[0].Line)
+	assert.Equal(t, 7, issues[3].Line)
+	assert.Equal(t, `step "Given a user" is outside any Background or scenario`, issues[0].Message)
+}
+
+func TestLint_SingleStepOutsideScenario(t *testing.T) {
+	issues := lintContent(t, "Feature: Login\n  Users sign in.\n  Given a user\n\n  Scenario: A\n    Then ok\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "steps-outside-scenario", issues[0].Rule)
+	assert.Equal(t, 3, issues[0].Line)
+}
+
+func TestLint_MultipleFtTags(t *testing.T) {
+	issues := lintContent(t, "Feature: Login\n  @ft:1 @smoke\n  @ft:2\n  Scenario: A\n    Then ok\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "multiple-ft-tags", issues[0].Rule)
+	assert.Equal(t, 4, issues[0].Line)
+	assert.Equal(t, `scenario "A" has 2 @ft tags: @ft:1 @ft:2`, issues[0].Message)
+}
+
+func TestLint_StrayErrorComment(t *testing.T) {
+	issues := lintContent(t, "# ft error: Examples must belong to a Scenario Outline (line 3)\nFeature: Login\n  Scenario: A\n    Then ok\n", nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "stray-error-comment", issues[0].Rule)
+	assert.Equal(t, 1, issues[0].Line)
+}
+
+func TestLint_ErrorCommentIsNotStrayWhileErrorRemains(t *testing.T) {
+	issues := lintContent(t, "# ft error: Examples must belong to a Scenario Outline (line 3)\nFeature: Login\n  Examples: Orphaned\n", nil)
+	assert.Equal(t, []string{"syntax"}, rulesOf(issues))
+}
+
+func TestLint_RestoredCommentIsNotStray(t *testing.T) {
+	issues := lintContent(t, "# ft error: scenario(s) @ft:2 restored because active test links exist — remove tests before removing scenarios (line 4)\nFeature: Login\n  @ft:2\n  Scenario: A\n    Then ok\n", nil)
+	assert.Empty(t, issues)
+}
+
+func TestLint_DisabledRulesDoNotRun(t *testing.T) {
+	content := "Feature: Login\n  Scenario: Unverified\n    Given a user\n"
+	assert.Len(t, lintContent(t, content, nil), 1)
+	assert.Empty(t, lintContent(t, content, Config{"no-then": false}))
+}
+
+func TestLint_IssuesSortedByFileAndLine(t *testing.T) {
+	a := NewFile("fts/b.ft", []byte("Feature: B\n  Scenario: A\n    Given x\n"))
+	b := NewFile("fts/a.ft", []byte("Feature: A\n  Scenario: Empty\n\n  Scenario: Empty\n"))
+	issues := Run([]*File{a, b}, nil)
+	var got []string
+	for _, is := range issues {
+		got = append(got, is.File+":"+is.Rule)
+	}
+	assert.Equal(t, []string{"fts/a.ft:no-steps", "fts/a.ft:duplicate-scenario-name", "fts/a.ft:no-steps", "fts/b.ft:no-then"}, got)
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig("# quiet down\nno-then = off\n\nsyntax=on\n")
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled("no-then"))
+	assert.True(t, cfg.Enabled("syntax"))
+	assert.True(t, cfg.Enabled("no-steps"))
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	_, err := ParseConfig("no-then off\n")
+	assert.EqualError(t, err, "line 1: expected <rule> = on|off")
+	_, err = ParseConfig("no-such-rule = off\n")
+	assert.EqualError(t, err, `line 1: unknown rule "no-such-rule"`)
+	_, err = ParseConfig("no-then = maybe\n")
+	assert.EqualError(t, err, `line 1: no-then must be on or off, got "maybe"`)
+}
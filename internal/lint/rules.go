@@ -0,0 +1,148 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chriserin/ft/internal/parser"
+)
+
+// scenarios returns every scenario in the file, feature-level first, then
+// each rule's.
+func scenarios(f *File) []parser.ScenarioDefinition {
+	all := append([]parser.ScenarioDefinition(nil), f.Doc.Feature.Scenarios...)
+	for _, r := range f.Doc.Feature.Rules {
+		all = append(all, r.Scenarios...)
+	}
+	return all
+}
+
+func checkSyntax(f *File) []Issue {
+	var issues []Issue
+	for _, pe := range f.Errors {
+		issues = append(issues, Issue{Line: pe.Line, Message: pe.Message})
+	}
+	return issues
+}
+
+func checkDuplicateNames(f *File) []Issue {
+	var issues []Issue
+	first := make(map[string]int)
+	for _, sd := range scenarios(f) {
+		name := sd.Scenario.Name
+		if line, ok := first[name]; ok {
+			issues = append(issues, Issue{Line: sd.Line, Message: fmt.Sprintf("scenario %q is already defined on line %d", name, line)})
+			continue
+		}
+		first[name] = sd.Line
+	}
+	return issues
+}
+
+func checkNoSteps(f *File) []Issue {
+	var issues []Issue
+	for _, sd := range scenarios(f) {
+		if len(sd.Scenario.StepGroups) == 0 {
+			issues = append(issues, Issue{Line: sd.Line, Message: fmt.Sprintf("scenario %q has no steps", sd.Scenario.Name)})
+		}
+	}
+	return issues
+}
+
+func checkNoThen(f *File) []Issue {
+	var issues []Issue
+	for _, sd := range scenarios(f) {
+		if len(sd.Scenario.StepGroups) == 0 {
+			continue // reported by no-steps
+		}
+		hasThen := false
+		for _, g := range sd.Scenario.StepGroups {
+			for _, kw := range f.Language.Then {
+				if g.Step.Keyword == kw {
+					hasThen = true
+				}
+			}
+		}
+		if !hasThen {
+			issues = append(issues, Issue{Line: sd.Line, Message: fmt.Sprintf("scenario %q has no %s step", sd.Scenario.Name, f.Language.Then[0])})
+		}
+	}
+	return issues
+}
+
+// checkStepsOutsideScenario finds steps that the parser folds into a Feature
+// or Rule description because no Background or scenario has started yet.
+func checkStepsOutsideScenario(f *File) []Issue {
+	lang := f.Language
+	var issues []Issue
+	inBlock := false
+	for i, l := range f.CST.Lines {
+		trimmed := strings.TrimSpace(l.Text)
+		switch l.Kind {
+		case parser.KeywordLine:
+			switch {
+			case startsWith(trimmed, lang.Feature), startsWith(trimmed, lang.Rule):
+				inBlock = false
+			case startsWith(trimmed, lang.Examples):
+				// still inside the outline
+			default: // Background, Scenario, Scenario Outline
+				inBlock = true
+			}
+		case parser.StepLine:
+			if !inBlock {
+				issues = append(issues, Issue{Line: i + 1, Message: fmt.Sprintf("step %q is outside any Background or scenario", trimmed)})
+			}
+		}
+	}
+	return issues
+}
+
+// startsWith reports whether trimmed starts with one of the block keywords.
+func startsWith(trimmed string, keywords []string) bool {
+	for _, kw := range keywords {
+		if strings.HasPrefix(trimmed, kw+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func checkMultipleFtTags(f *File) []Issue {
+	var issues []Issue
+	for _, sd := range scenarios(f) {
+		var ftTags []string
+		for _, tag := range sd.Tags {
+			if strings.HasPrefix(tag.Name, "@ft:") {
+				ftTags = append(ftTags, tag.Name)
+			}
+		}
+		if len(ftTags) > 1 {
+			issues = append(issues, Issue{Line: sd.Line, Message: fmt.Sprintf("scenario %q has %d @ft tags: %s", sd.Scenario.Name, len(ftTags), strings.Join(ftTags, " "))})
+		}
+	}
+	return issues
+}
+
+// restoredCommentRe matches the comment sync writes above scenarios it
+// wrote back because tests link to them.
+var restoredCommentRe = regexp.MustCompile(`^# ft error: scenario\(s\) (@ft:\d+ )+restored `)
+
+// checkStrayErrorComments reports `# ft error:` comments left in a file that
+// now parses cleanly. While the file still has errors, the comments are
+// current and the syntax rule reports the errors themselves. The comment
+// about restored scenarios isn't about parsing: sync removes it once no test
+// links to them, which lint can't see.
+func checkStrayErrorComments(f *File) []Issue {
+	if len(f.Errors) > 0 {
+		return nil
+	}
+	var issues []Issue
+	for i, l := range f.CST.Lines {
+		text := strings.TrimSpace(l.Text)
+		if l.Kind == parser.CommentLine && strings.HasPrefix(text, "# ft error:") && !restoredCommentRe.MatchString(text) {
+			issues = append(issues, Issue{Line: i + 1, Message: "stale error comment; the file now parses without errors"})
+		}
+	}
+	return issues
+}
@@ -3,13 +3,15 @@ package parser
 // Layer 1: Tree-sitter-compatible AST types
 
 type Document struct {
-	Feature *Feature
+	Feature  *Feature
+	Language string // language code from the `# language:` header, "en" by default
 }
 
 type Feature struct {
 	Header     FeatureHeader
 	Background *Background
 	Scenarios  []ScenarioDefinition
+	Rules      []Rule
 }
 
 type FeatureHeader struct {
@@ -21,6 +23,16 @@ type FeatureHeader struct {
 type Background struct {
 	Description string
 	StepGroups  []StepGroup
+	Line        int // 1-based line number of Background: line
+}
+
+type Rule struct {
+	Tags        []Tag
+	Name        string
+	Description string
+	Background  *Background
+	Scenarios   []ScenarioDefinition
+	Line        int // 1-based line number of Rule: line
 }
 
 type ScenarioDefinition struct {
@@ -30,9 +42,19 @@ type ScenarioDefinition struct {
 }
 
 type Scenario struct {
+	Outline     bool // true for Scenario Outline:
 	Name        string
 	Description string
 	StepGroups  []StepGroup
+	Examples    []Examples // only populated for a Scenario Outline
+}
+
+type Examples struct {
+	Tags        []Tag
+	Name        string
+	Description string
+	Table       *DataTable
+	Line        int // 1-based line number of Examples: line
 }
 
 type Tag struct {
@@ -45,9 +67,10 @@ type StepGroup struct {
 }
 
 type Step struct {
-	Keyword  string // Given, When, Then, And, But, *
+	Keyword  string // Given, When, Then, And, But, * (or the file language's equivalent)
 	Text     string
 	Argument *StepArgument
+	Line     int // 1-based line number of the step
 }
 
 type StepArgument struct {
@@ -65,7 +88,14 @@ type DataTable struct {
 	Rows      [][]string
 }
 
+// ParseError is a problem found while parsing. Line through EndLine is the
+// broken region; scenarios overlapping it are skipped while the rest of the
+// file is still used. A Fatal error means nothing in the file can be
+// trusted, e.g. an unknown language header.
 type ParseError struct {
-	Line    int
+	Line    int // 1-based first line of the broken region
+	EndLine int // 1-based last line of the broken region
+	Column  int // 1-based column of the offending construct
 	Message string
+	Fatal   bool
 }
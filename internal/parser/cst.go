@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// CST is a lossless, line-oriented concrete syntax tree of a .ft file.
+// Bytes reproduces the original content byte-for-byte — byte order mark,
+// line endings, indentation, trailing whitespace and the presence or absence
+// of a final newline. The edit methods change only the lines they touch.
+//
+// Line numbers are 1-based and refer to the tree's current state, so callers
+// making several edits should work from the bottom of the file up.
+type CST struct {
+	BOM      bool
+	Language string // language code used to classify lines
+	Lines    []CSTLine
+}
+
+// CSTLine is one physical line of a .ft file.
+type CSTLine struct {
+	Kind   LineKind
+	Indent string // leading spaces and tabs
+	Text   string // everything after Indent, excluding the line ending
+	EOL    string // "\n", "\r\n", or "" for a final line without a newline
+}
+
+// LineKind classifies a CSTLine by the Gherkin construct it holds.
+type LineKind int
+
+const (
+	TextLine      LineKind = iota // description or other free text
+	BlankLine                     // empty or whitespace only
+	CommentLine                   // starts with #
+	TagLine                       // starts with @
+	KeywordLine                   // Feature:, Background:, Scenario:, …
+	StepLine                      // Given, When, Then, And, But, *
+	TableRowLine                  // starts with |
+	DocStringLine                 // a doc string delimiter or content line
+)
+
+const bom = "\uFEFF"
+
+// ParseCST splits content into a lossless CST, classifying each line using
+// the keywords of the file's `# language:` header.
+func ParseCST(content []byte) *CST {
+	s := string(content)
+	cst := &CST{}
+	if rest, ok := strings.CutPrefix(s, bom); ok {
+		cst.BOM = true
+		s = rest
+	}
+
+	for s != "" {
+		var raw, eol string
+		if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+			raw, s = s[:idx], s[idx+1:]
+			eol = "\n"
+			if r, ok := strings.CutSuffix(raw, "\r"); ok {
+				raw = r
+				eol = "\r\n"
+			}
+		} else {
+			raw, s = s, ""
+		}
+		text := strings.TrimLeft(raw, " \t")
+		cst.Lines = append(cst.Lines, CSTLine{
+			Indent: raw[:len(raw)-len(text)],
+			Text:   text,
+			EOL:    eol,
+		})
+	}
+
+	cst.classify()
+	return cst
+}
+
+// classify sets the Kind of every line.
+func (c *CST) classify() {
+	lang := LanguageFor(DefaultLanguage)
+	header := true
+	docString := ""
+	for i := range c.Lines {
+		l := &c.Lines[i]
+		trimmed := strings.TrimSpace(l.Text)
+
+		if docString != "" {
+			l.Kind = DocStringLine
+			if trimmed == docString {
+				docString = ""
+			}
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			l.Kind = BlankLine
+		case strings.HasPrefix(trimmed, "#"):
+			l.Kind = CommentLine
+			if code, ok := languageHeader(trimmed); ok && header {
+				lang = LanguageFor(code)
+			}
+		case isDocStringDelimiter(trimmed):
+			l.Kind = DocStringLine
+			docString = `"""`
+			if strings.HasPrefix(trimmed, "```") {
+				docString = "```"
+			}
+		case isTagLine(trimmed):
+			l.Kind = TagLine
+		case lang.IsKeyword(trimmed):
+			l.Kind = KeywordLine
+		case isTableRow(trimmed):
+			l.Kind = TableRowLine
+		default:
+			if _, _, ok := splitStep(lang, trimmed); ok {
+				l.Kind = StepLine
+			} else {
+				l.Kind = TextLine
+			}
+		}
+		if l.Kind != BlankLine && l.Kind != CommentLine {
+			header = false
+		}
+	}
+	c.Language = lang.Code
+}
+
+// Bytes renders the CST back to file content.
+func (c *CST) Bytes() []byte {
+	var buf bytes.Buffer
+	if c.BOM {
+		buf.WriteString(bom)
+	}
+	for _, l := range c.Lines {
+		buf.WriteString(l.Indent)
+		buf.WriteString(l.Text)
+		buf.WriteString(l.EOL)
+	}
+	return buf.Bytes()
+}
+
+// String renders the CST back to file content.
+func (c *CST) String() string {
+	return string(c.Bytes())
+}
+
+// Line returns the 1-based line n.
+func (c *CST) Line(n int) (CSTLine, error) {
+	if n < 1 || n > len(c.Lines) {
+		return CSTLine{}, fmt.Errorf("line %d out of range (1-%d)", n, len(c.Lines))
+	}
+	return c.Lines[n-1], nil
+}
+
+// InsertTag inserts a tag line holding tag above line n, indented to match it.
+func (c *CST) InsertTag(n int, tag string) error {
+	return c.insertAbove(n, TagLine, tag)
+}
+
+// ReplaceTag replaces the tag from with to on the tag line n, leaving the
+// rest of the line untouched.
+func (c *CST) ReplaceTag(n int, from, to string) error {
+	l, err := c.Line(n)
+	if err != nil {
+		return err
+	}
+	if l.Kind != TagLine {
+		return fmt.Errorf("line %d is not a tag line", n)
+	}
+	for _, loc := range tagPattern.FindAllStringIndex(l.Text, -1) {
+		if l.Text[loc[0]:loc[1]] == from {
+			c.Lines[n-1].Text = l.Text[:loc[0]] + to + l.Text[loc[1]:]
+			return nil
+		}
+	}
+	return fmt.Errorf("line %d has no tag %s", n, from)
+}
+
+// AddComment inserts a "# <text>" comment line above line n, indented to
+// match it. n may be one past the last line to append at the end of the file.
+func (c *CST) AddComment(n int, text string) error {
+	return c.insertAbove(n, CommentLine, "# "+text)
+}
+
+// RemoveComment deletes the comment line n.
+func (c *CST) RemoveComment(n int) error {
+	l, err := c.Line(n)
+	if err != nil {
+		return err
+	}
+	if l.Kind != CommentLine {
+		return fmt.Errorf("line %d is not a comment", n)
+	}
+	// Removing the final line of a file that ends without a newline moves
+	// that state onto the new final line.
+	if n == len(c.Lines) && n > 1 && l.EOL == "" {
+		c.Lines[n-2].EOL = ""
+	}
+	c.Lines = append(c.Lines[:n-1], c.Lines[n:]...)
+	return nil
+}
+
+// insertAbove inserts a line of the given kind and text above line n,
+// copying line n's indentation. The new line uses the file's line ending.
+func (c *CST) insertAbove(n int, kind LineKind, text string) error {
+	if n < 1 || n > len(c.Lines)+1 {
+		return fmt.Errorf("line %d out of range (1-%d)", n, len(c.Lines)+1)
+	}
+	line := CSTLine{Kind: kind, Text: text, EOL: c.EOL()}
+	if n <= len(c.Lines) {
+		line.Indent = c.Lines[n-1].Indent
+	} else if n > 1 && c.Lines[n-2].EOL == "" {
+		// Appending after a final line without a newline: give that line the
+		// newline and leave the file still ending without one.
+		c.Lines[n-2].EOL = c.EOL()
+		line.EOL = ""
+	}
+	c.Lines = append(c.Lines, CSTLine{})
+	copy(c.Lines[n:], c.Lines[n-1:])
+	c.Lines[n-1] = line
+	return nil
+}
+
+// EOL returns the line ending new lines should use: the file's most common
+// one, the first of them on a tie, or "\n" if the file has none. A file
+// with the odd stray ending keeps its style.
+func (c *CST) EOL() string {
+	counts := make(map[string]int)
+	first := ""
+	for _, l := range c.Lines {
+		if l.EOL == "" {
+			continue
+		}
+		if first == "" {
+			first = l.EOL
+		}
+		counts[l.EOL]++
+	}
+	switch {
+	case first == "":
+		return "\n"
+	case counts["\r\n"] > counts["\n"]:
+		return "\r\n"
+	case counts["\n"] > counts["\r\n"]:
+		return "\n"
+	}
+	return first
+}
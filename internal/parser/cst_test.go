@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCST_RoundTrip(t *testing.T) {
+	cases := map[string]string{
+		"empty":                "",
+		"lf":                   "Feature: Login\n  Scenario: User logs in\n    Given a user\n",
+		"crlf":                 "Feature: Login\r\n  Scenario: User logs in\r\n    Given a user\r\n",
+		"mixed line endings":   "Feature: Login\r\n  Scenario: User logs in\n    Given a user\r\n",
+		"no final newline":     "Feature: Login\n  Scenario: User logs in",
+		"bom":                  "\uFEFFFeature: Login\n",
+		"tabs and trailing ws": "Feature: Login \t\n\tScenario: User logs in  \n\t\tGiven a user\n",
+		"blank lines":          "\n\nFeature: Login\n\n\n",
+		"doc string":           "Feature: Login\n  Scenario: A\n    Given a doc\n      \"\"\"\n  Scenario: not a keyword\n      \"\"\"\n",
+		"lone carriage return": "Feature: Login\r",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, content, string(ParseCST([]byte(content)).Bytes()))
+		})
+	}
+}
+
+func TestCST_LineKinds(t *testing.T) {
+	cst := ParseCST([]byte(`# language: de
+Funktionalität: Anmeldung
+
+  @smoke
+  Szenario: Erfolgreiche Anmeldung
+    Angenommen ein Benutzer
+      """
+      Szenario: text
+      """
+      | a |
+    nur Text
+`))
+	var kinds []LineKind
+	for _, l := range cst.Lines {
+		kinds = append(kinds, l.Kind)
+	}
+	assert.Equal(t, []LineKind{
+		CommentLine, KeywordLine, BlankLine, TagLine, KeywordLine, StepLine,
+		DocStringLine, DocStringLine, DocStringLine, TableRowLine, TextLine,
+	}, kinds)
+}
+
+func TestCST_InsertTagMatchesIndentAndLineEnding(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\r\n\tScenario: User logs in\r\n"))
+	require.NoError(t, cst.InsertTag(2, "@ft:1"))
+	assert.Equal(t, "Feature: Login\r\n\t@ft:1\r\n\tScenario: User logs in\r\n", cst.String())
+	assert.Equal(t, TagLine, cst.Lines[1].Kind)
+}
+
+func TestCST_InsertTagAboveFinalLineWithoutNewline(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\n  Scenario: User logs in"))
+	require.NoError(t, cst.InsertTag(2, "@ft:1"))
+	assert.Equal(t, "Feature: Login\n  @ft:1\n  Scenario: User logs in", cst.String())
+}
+
+func TestCST_InsertTagOutOfRange(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\n"))
+	assert.Error(t, cst.InsertTag(0, "@ft:1"))
+	assert.Error(t, cst.InsertTag(3, "@ft:1"))
+}
+
+func TestCST_ReplaceTag(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\n  @smoke  @ft:3 @wip\n  Scenario: User logs in\n"))
+	require.NoError(t, cst.ReplaceTag(2, "@ft:3", "@ft:12"))
+	assert.Equal(t, "Feature: Login\n  @smoke  @ft:12 @wip\n  Scenario: User logs in\n", cst.String())
+}
+
+func TestCST_ReplaceTagErrors(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\n  @smoke\n"))
+	assert.EqualError(t, cst.ReplaceTag(1, "@smoke", "@fast"), "line 1 is not a tag line")
+	assert.EqualError(t, cst.ReplaceTag(2, "@ft:1", "@ft:2"), "line 2 has no tag @ft:1")
+}
+
+func TestCST_AddCommentAtTopKeepsBOM(t *testing.T) {
+	cst := ParseCST([]byte("\uFEFFFeature: Login\r\n"))
+	require.NoError(t, cst.AddComment(1, "ft error: bad (line 1)"))
+	assert.Equal(t, "\uFEFF# ft error: bad (line 1)\r\nFeature: Login\r\n", cst.String())
+}
+
+func TestCST_AddCommentAtEnd(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login"))
+	require.NoError(t, cst.AddComment(2, "done"))
+	assert.Equal(t, "Feature: Login\n# done", cst.String())
+}
+
+func TestCST_AddCommentToEmptyFile(t *testing.T) {
+	cst := ParseCST(nil)
+	require.NoError(t, cst.AddComment(1, "note"))
+	assert.Equal(t, "# note\n", cst.String())
+}
+
+func TestCST_RemoveComment(t *testing.T) {
+	cst := ParseCST([]byte("# ft error: bad (line 3)\r\nFeature: Login\r\n"))
+	require.NoError(t, cst.RemoveComment(1))
+	assert.Equal(t, "Feature: Login\r\n", cst.String())
+}
+
+func TestCST_RemoveFinalCommentWithoutNewline(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\n# trailing"))
+	require.NoError(t, cst.RemoveComment(2))
+	assert.Equal(t, "Feature: Login", cst.String())
+}
+
+func TestCST_RemoveCommentRejectsOtherLines(t *testing.T) {
+	cst := ParseCST([]byte("Feature: Login\n"))
+	assert.EqualError(t, cst.RemoveComment(1), "line 1 is not a comment")
+}
+
+func TestCST_EOL(t *testing.T) {
+	cases := map[string]string{
+		"":                    "\n",
+		"Feature: Login":      "\n",
+		"Feature: Login\r\n":  "\r\n",
+		"A\n B\r\n C\r\n":     "\r\n",
+		"A\r\n B\n C\n":       "\n",
+		"A\r\n B\n":           "\r\n",
+		"\uFEFFA\r\n B\r\n C": "\r\n",
+	}
+	for content, want := range cases {
+		assert.Equal(t, want, ParseCST([]byte(content)).EOL(), "%q", content)
+	}
+}
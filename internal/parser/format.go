@@ -0,0 +1,367 @@
+package parser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// indentStep is the indentation added for each level of nesting.
+const indentStep = 2
+
+// Format rewrites a .ft file into the canonical layout:
+//
+//   - two-space indentation per level: Feature at 0, its Background, Scenarios
+//     and Rules at 2, a Rule's Background and Scenarios at 4, steps, Examples
+//     and descriptions one level inside their block, step arguments and
+//     Examples tables one level further
+//   - step keywords padded so step text lines up one column past the
+//     language's longest primary keyword, e.g. "When  they" in English
+//   - consecutive tag lines merged onto one line with any @ft:<id> tag first
+//   - data table columns aligned
+//   - exactly one blank line before each Background, Scenario and Rule (and
+//     the tags and comments above it), no leading or trailing blank lines,
+//     a single line ending style and a final newline
+//
+// Lines inside a doc string keep their content and relative indentation.
+// Blank lines inside a scenario are left alone, so a formatted scenario's
+// steps normalize to the same text as before.
+func Format(content []byte) []byte {
+	cst := ParseCST(content)
+	lang := LanguageFor(cst.Language)
+	eol := cst.EOL()
+
+	lines := mergeTagLines(cst.Lines)
+	indentLines(lang, lines)
+	lines = normalizeBlankLines(lang, lines)
+	alignTables(lines)
+	for i := range lines {
+		lines[i].EOL = eol
+	}
+
+	cst.Lines = lines
+	return cst.Bytes()
+}
+
+// mergeTagLines joins each run of consecutive tag-only lines into one line,
+// moving @ft:<id> tags to the front.
+func mergeTagLines(lines []CSTLine) []CSTLine {
+	var out []CSTLine
+	for i := 0; i < len(lines); {
+		if !isTagOnlyLine(lines[i]) {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+		var ftTags, otherTags []string
+		j := i
+		for ; j < len(lines) && isTagOnlyLine(lines[j]); j++ {
+			for _, tag := range parseTags(lines[j].Text) {
+				if strings.HasPrefix(tag.Name, "@ft:") {
+					ftTags = append(ftTags, tag.Name)
+				} else {
+					otherTags = append(otherTags, tag.Name)
+				}
+			}
+		}
+		merged := lines[i]
+		merged.Text = strings.Join(append(ftTags, otherTags...), " ")
+		out = append(out, merged)
+		i = j
+	}
+	return out
+}
+
+// isTagOnlyLine reports whether l is a tag line holding nothing but tags,
+// e.g. no trailing comment.
+func isTagOnlyLine(l CSTLine) bool {
+	return l.Kind == TagLine && strings.TrimSpace(tagPattern.ReplaceAllString(l.Text, "")) == ""
+}
+
+// indentLines sets the canonical indentation and inner spacing of every line.
+// Tags and comments take the indentation of the line they precede.
+func indentLines(lang *Language, lines []CSTLine) {
+	stepWidth := stepKeywordWidth(lang)
+
+	seenFeature := false
+	base := indentStep        // indentation of Background/Scenario/Rule keywords
+	descIndent := 0           // indentation of Feature/Rule description lines
+	block := -1               // indentation of the current Background/Scenario, -1 outside one
+	inExamples := false       // inside an Examples block of the current outline
+	var pending []int         // tag and comment lines waiting for the next line's indent
+	lastIndent := 0           // indentation of the last significant line
+	var docOld, docNew string // doc string opener's original and new indentation
+	docDelim := ""
+
+	setIndent := func(i, n int) {
+		lines[i].Indent = strings.Repeat(" ", n)
+		for _, p := range pending {
+			lines[p].Indent = lines[i].Indent
+		}
+		pending = nil
+		lastIndent = n
+	}
+
+	for i := range lines {
+		l := &lines[i]
+		trimmed := strings.TrimSpace(l.Text)
+
+		if docDelim != "" {
+			if trimmed == docDelim {
+				l.Indent, l.Text = docNew, trimmed
+				docDelim = ""
+				continue
+			}
+			if raw := l.Indent + l.Text; strings.HasPrefix(raw, docOld) && raw != "" {
+				raw = docNew + raw[len(docOld):]
+				l.Text = strings.TrimLeft(raw, " \t")
+				l.Indent = raw[:len(raw)-len(l.Text)]
+			}
+			continue
+		}
+
+		switch l.Kind {
+		case BlankLine:
+			l.Indent, l.Text = "", ""
+
+		case TagLine, CommentLine:
+			l.Text = strings.TrimRight(l.Text, " \t")
+			pending = append(pending, i)
+
+		case KeywordLine:
+			kw, rest := lang.splitKeyword(trimmed)
+			l.Text = kw
+			if rest != "" {
+				l.Text += " " + rest
+			}
+			switch {
+			case lang.is(trimmed, lang.Feature):
+				seenFeature = true
+				descIndent = indentStep
+				block = -1
+				setIndent(i, 0)
+			case lang.is(trimmed, lang.Rule):
+				base = 2 * indentStep
+				descIndent = 2 * indentStep
+				block = -1
+				inExamples = false
+				setIndent(i, indentStep)
+			case lang.is(trimmed, lang.Examples):
+				if block < 0 {
+					setIndent(i, base)
+				} else {
+					setIndent(i, block+indentStep)
+				}
+				inExamples = true
+			default: // Background, Scenario, Scenario Outline
+				block = base
+				inExamples = false
+				setIndent(i, base)
+			}
+
+		case StepLine:
+			if block < 0 {
+				l.Text = trimmed
+				setIndent(i, outsideBlockIndent(seenFeature, descIndent))
+				break
+			}
+			kw, text, _ := splitStep(lang, trimmed)
+			l.Text = padStepKeyword(kw, stepWidth) + text
+			setIndent(i, block+indentStep)
+
+		case TableRowLine:
+			l.Text = trimmed
+			if block < 0 {
+				setIndent(i, outsideBlockIndent(seenFeature, descIndent))
+			} else {
+				setIndent(i, block+2*indentStep)
+			}
+
+		case DocStringLine:
+			docDelim = `"""`
+			if strings.HasPrefix(trimmed, "```") {
+				docDelim = "```"
+			}
+			docOld = l.Indent
+			l.Text = trimmed
+			if block < 0 {
+				setIndent(i, outsideBlockIndent(seenFeature, descIndent))
+			} else {
+				setIndent(i, block+2*indentStep)
+			}
+			docNew = l.Indent
+
+		default: // TextLine
+			l.Text = trimmed
+			switch {
+			case block < 0:
+				setIndent(i, outsideBlockIndent(seenFeature, descIndent))
+			case inExamples:
+				setIndent(i, block+2*indentStep)
+			default:
+				setIndent(i, block+indentStep)
+			}
+		}
+	}
+
+	for _, p := range pending {
+		lines[p].Indent = strings.Repeat(" ", lastIndent)
+	}
+}
+
+// outsideBlockIndent is the indentation of a line that isn't inside a
+// Background or Scenario: a description line, or anything before Feature:.
+func outsideBlockIndent(seenFeature bool, descIndent int) int {
+	if !seenFeature {
+		return 0
+	}
+	return descIndent
+}
+
+// stepKeywordWidth returns the width step keywords are padded to: the
+// longest of the language's primary Given/When/Then/And/But keywords.
+func stepKeywordWidth(lang *Language) int {
+	width := 0
+	for _, kws := range [][]string{lang.Given, lang.When, lang.Then, lang.And, lang.But} {
+		if n := utf8.RuneCountInString(kws[0]); n > width {
+			width = n
+		}
+	}
+	return width
+}
+
+// padStepKeyword returns kw followed by the spaces that start step text one
+// column past width, and always at least one. Elided keywords such as French
+// "Lorsqu'" are never padded, and "*" bullets get a single space.
+func padStepKeyword(kw string, width int) string {
+	if strings.HasSuffix(kw, "'") {
+		return kw
+	}
+	if kw == "*" {
+		return kw + " "
+	}
+	return kw + strings.Repeat(" ", max(1, width+1-utf8.RuneCountInString(kw)))
+}
+
+// splitKeyword splits a keyword line into its keyword (with colon) and the
+// trimmed text after it.
+func (l *Language) splitKeyword(trimmed string) (string, string) {
+	for _, kw := range l.SectionKeywords() {
+		if rest, ok := strings.CutPrefix(trimmed, kw); ok {
+			return kw, strings.TrimSpace(rest)
+		}
+	}
+	return trimmed, ""
+}
+
+// normalizeBlankLines leaves exactly one blank line before each Background,
+// Scenario and Rule together with the tags and comments above it, and drops
+// leading and trailing blank lines.
+func normalizeBlankLines(lang *Language, lines []CSTLine) []CSTLine {
+	var out []CSTLine
+	for _, l := range lines {
+		if l.Kind == KeywordLine && isBlockStart(lang, strings.TrimSpace(l.Text)) {
+			k := len(out)
+			for k > 0 && (out[k-1].Kind == BlankLine || out[k-1].Kind == TagLine || out[k-1].Kind == CommentLine) {
+				k--
+			}
+			var lead []CSTLine
+			for _, p := range out[k:] {
+				if p.Kind != BlankLine {
+					lead = append(lead, p)
+				}
+			}
+			out = out[:k]
+			if len(out) > 0 {
+				out = append(out, CSTLine{Kind: BlankLine})
+			}
+			out = append(out, lead...)
+		}
+		out = append(out, l)
+	}
+
+	for len(out) > 0 && out[0].Kind == BlankLine {
+		out = out[1:]
+	}
+	for len(out) > 0 && out[len(out)-1].Kind == BlankLine {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func isBlockStart(lang *Language, trimmed string) bool {
+	return lang.is(trimmed, lang.Background) || lang.isScenario(trimmed) || lang.is(trimmed, lang.Rule)
+}
+
+// alignTables pads the cells of each run of consecutive table rows so their
+// columns line up. A run with text after a row's last pipe is left alone.
+func alignTables(lines []CSTLine) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != TableRowLine {
+			i++
+			continue
+		}
+		j := i
+		for j < len(lines) && lines[j].Kind == TableRowLine {
+			j++
+		}
+		alignTable(lines[i:j])
+		i = j
+	}
+}
+
+func alignTable(rows []CSTLine) {
+	cells := make([][]string, len(rows))
+	var widths []int
+	for r, row := range rows {
+		var trailing string
+		cells[r], trailing = SplitTableRow(row.Text)
+		if trailing != "" {
+			return
+		}
+		for c, cell := range cells[r] {
+			if c == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[c] = max(widths[c], utf8.RuneCountInString(cell))
+		}
+	}
+
+	for r := range rows {
+		var b strings.Builder
+		b.WriteString("|")
+		for c, cell := range cells[r] {
+			b.WriteString(" ")
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell)))
+			b.WriteString(" |")
+		}
+		rows[r].Text = b.String()
+	}
+}
+
+// SplitTableRow splits a trimmed table row on unescaped pipes, returning the
+// trimmed cells with their escapes intact and any text after the last pipe.
+func SplitTableRow(trimmed string) ([]string, string) {
+	var cells []string
+	var cell strings.Builder
+	started := false
+	runes := []rune(trimmed)
+	for k := 0; k < len(runes); k++ {
+		r := runes[k]
+		switch {
+		case r == '\\' && k+1 < len(runes):
+			cell.WriteRune(r)
+			k++
+			cell.WriteRune(runes[k])
+		case r == '|':
+			if started {
+				cells = append(cells, strings.TrimSpace(cell.String()))
+			}
+			cell.Reset()
+			started = true
+		default:
+			cell.WriteRune(r)
+		}
+	}
+	return cells, strings.TrimSpace(cell.String())
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat_Indentation(t *testing.T) {
+	in := `Feature: Login
+Users log in.
+Background:
+Given a user
+Scenario: User logs in
+      Given a user
+When they log in
+`
+	want := `Feature: Login
+  Users log in.
+
+  Background:
+    Given a user
+
+  Scenario: User logs in
+    Given a user
+    When  they log in
+`
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_RuleIndentation(t *testing.T) {
+	in := `Feature: Refunds
+Rule: Refunds need a receipt
+Purchases without a receipt are store credit.
+Background:
+Given a purchase
+Scenario Outline: Refund <n>
+Given <n> items
+Examples: Small
+| n |
+| 1 |
+`
+	want := `Feature: Refunds
+
+  Rule: Refunds need a receipt
+    Purchases without a receipt are store credit.
+
+    Background:
+      Given a purchase
+
+    Scenario Outline: Refund <n>
+      Given <n> items
+      Examples: Small
+        | n |
+        | 1 |
+`
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_StepKeywordPadding(t *testing.T) {
+	in := "Feature: Login\n  Scenario: A\n    Given a\n    When b\n    Then    c\n    And d\n    But e\n    * f\n"
+	want := "Feature: Login\n\n  Scenario: A\n    Given a\n    When  b\n    Then  c\n    And   d\n    But   e\n    * f\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_StepKeywordPaddingFollowsLanguage(t *testing.T) {
+	in := "# language: de\nFunktionalität: Anmeldung\n  Szenario: A\n    Angenommen a\n    Wenn b\n    Gegeben seien c\n"
+	want := "# language: de\nFunktionalität: Anmeldung\n\n  Szenario: A\n    Angenommen a\n    Wenn       b\n    Gegeben seien c\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_FtTagFirstOnMergedTagLine(t *testing.T) {
+	in := "Feature: Login\n  @smoke   @wip\n  @ft:7\n  Scenario: A\n    Given a\n"
+	want := "Feature: Login\n\n  @ft:7 @smoke @wip\n  Scenario: A\n    Given a\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_TagLineWithCommentIsNotMerged(t *testing.T) {
+	in := "Feature: Login\n\n  @smoke # flaky\n  @ft:7\n  Scenario: A\n    Given a\n"
+	assert.Equal(t, in, string(Format([]byte(in))))
+}
+
+func TestFormat_AlignsTables(t *testing.T) {
+	in := "Feature: Login\n\n  Scenario: A\n    Given users\n      |name|role|\n      | alice |admin|\n      |b\\|c||\n"
+	want := "Feature: Login\n\n  Scenario: A\n    Given users\n      | name  | role  |\n      | alice | admin |\n      | b\\|c  |       |\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_AlignsByCharactersNotBytes(t *testing.T) {
+	in := "Feature: Login\n\n  Scenario: A\n    Given users\n      | né | x |\n      | abc | y |\n"
+	want := "Feature: Login\n\n  Scenario: A\n    Given users\n      | né  | x |\n      | abc | y |\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_BlankLinesBetweenScenarios(t *testing.T) {
+	in := "\n\nFeature: Login\n  Scenario: A\n    Given a\n\n\n\n  # note\n\n  @ft:2\n  Scenario: B\n    Given b\n\n    When c\n\n\n"
+	want := "Feature: Login\n\n  Scenario: A\n    Given a\n\n  # note\n  @ft:2\n  Scenario: B\n    Given b\n\n    When  c\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_DocStringKeepsRelativeIndentation(t *testing.T) {
+	in := "Feature: Login\n\n  Scenario: A\n    Given json\n  \"\"\"json\n  {\n    \"a\": 1   \n  }\n\n  \"\"\"\n"
+	want := "Feature: Login\n\n  Scenario: A\n    Given json\n      \"\"\"json\n      {\n        \"a\": 1   \n      }\n\n      \"\"\"\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_KeepsLineEndingAndBOM(t *testing.T) {
+	in := "\uFEFFFeature: Login\r\n  Scenario: A\n    Given a"
+	want := "\uFEFFFeature: Login\r\n\r\n  Scenario: A\r\n    Given a\r\n"
+	assert.Equal(t, want, string(Format([]byte(in))))
+}
+
+func TestFormat_Idempotent(t *testing.T) {
+	in := "@wip\nFeature:   Login  \n Users.\n@smoke\n@ft:3\nScenario:  A  \n  When x\n   |a|bb|\n  \"\"\"\n   doc\n  \"\"\"\nRule: R\nScenario Outline: O\nGiven <x>\n@small\nExamples:\n|x|\n|1|\n"
+	once := Format([]byte(in))
+	assert.Equal(t, string(once), string(Format(once)))
+}
+
+func TestFormat_PreservesParse(t *testing.T) {
+	in := []byte("Feature: Login\nScenario: A\n  Given a\n      When they log in\n   |name|role|\n   |alice|admin|\n@ft:4\nScenario Outline: B\n Given <x>\nExamples:\n  |x|\n  |1|\n")
+	before, errs := Parse("login.ft", in)
+	assert.Empty(t, errs)
+	after, errs := Parse("login.ft", Format(in))
+	assert.Empty(t, errs)
+
+	for i := range before.Feature.Scenarios {
+		b, a := before.Feature.Scenarios[i], after.Feature.Scenarios[i]
+		assert.Equal(t, b.Tags, a.Tags)
+		assert.Equal(t, b.Scenario.Name, a.Scenario.Name)
+		assert.Equal(t, len(b.Scenario.StepGroups), len(a.Scenario.StepGroups))
+		for g := range b.Scenario.StepGroups {
+			assert.Equal(t, b.Scenario.StepGroups[g].Step.Text, a.Scenario.StepGroups[g].Step.Text)
+			assert.Equal(t, b.Scenario.StepGroups[g].Step.Argument, a.Scenario.StepGroups[g].Step.Argument)
+		}
+		assert.Equal(t, len(b.Scenario.Examples), len(a.Scenario.Examples))
+	}
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Language is the keyword table for one Gherkin language. Block keywords are
+// stored without their trailing colon, step keywords without trailing space.
+type Language struct {
+	Code            string
+	Feature         []string
+	Background      []string
+	Rule            []string
+	Scenario        []string
+	ScenarioOutline []string
+	Examples        []string
+	Given           []string
+	When            []string
+	Then            []string
+	And             []string
+	But             []string
+
+	// Built once from the keywords above, as the parser checks every line
+	// against them
+	sectionKeywords []string
+	stepKeywords    []string
+}
+
+// DefaultLanguage is used when a file has no `# language:` header.
+const DefaultLanguage = "en"
+
+// Languages holds every supported keyword table, keyed by language code.
+// Keywords follow the official Gherkin translations.
+var Languages = map[string]*Language{
+	"en": {
+		Code:            "en",
+		Feature:         []string{"Feature"},
+		Background:      []string{"Background"},
+		Rule:            []string{"Rule"},
+		Scenario:        []string{"Scenario", "Example"},
+		ScenarioOutline: []string{"Scenario Outline"},
+		Examples:        []string{"Examples", "Scenarios"},
+		Given:           []string{"Given"},
+		When:            []string{"When"},
+		Then:            []string{"Then"},
+		And:             []string{"And", "*"},
+		But:             []string{"But"},
+	},
+	"de": {
+		Code:            "de",
+		Feature:         []string{"Funktionalität", "Funktion"},
+		Background:      []string{"Grundlage", "Hintergrund", "Voraussetzungen", "Vorbedingungen"},
+		Rule:            []string{"Rule", "Regel"},
+		Scenario:        []string{"Beispiel", "Szenario"},
+		ScenarioOutline: []string{"Szenariogrundriss", "Szenarien"},
+		Examples:        []string{"Beispiele"},
+		Given:           []string{"Angenommen", "Gegeben sei", "Gegeben seien"},
+		When:            []string{"Wenn"},
+		Then:            []string{"Dann"},
+		And:             []string{"Und", "*"},
+		But:             []string{"Aber"},
+	},
+	"es": {
+		Code:            "es",
+		Feature:         []string{"Característica", "Necesidad del negocio", "Requisito"},
+		Background:      []string{"Antecedentes"},
+		Rule:            []string{"Regla", "Regla de negocio"},
+		Scenario:        []string{"Ejemplo", "Escenario"},
+		ScenarioOutline: []string{"Esquema del escenario"},
+		Examples:        []string{"Ejemplos"},
+		Given:           []string{"Dado", "Dada", "Dados", "Dadas"},
+		When:            []string{"Cuando"},
+		Then:            []string{"Entonces"},
+		And:             []string{"Y", "E", "*"},
+		But:             []string{"Pero"},
+	},
+	"fr": {
+		Code:            "fr",
+		Feature:         []string{"Fonctionnalité"},
+		Background:      []string{"Contexte"},
+		Rule:            []string{"Règle"},
+		Scenario:        []string{"Exemple", "Scénario"},
+		ScenarioOutline: []string{"Plan du scénario", "Plan du Scénario"},
+		Examples:        []string{"Exemples"},
+		Given:           []string{"Soit", "Sachant que", "Sachant qu'", "Sachant", "Etant donné que", "Etant donné qu'", "Etant donné", "Etant donnée", "Etant donnés", "Etant données", "Étant donné que", "Étant donné qu'", "Étant donné", "Étant donnée", "Étant donnés", "Étant données"},
+		When:            []string{"Quand", "Lorsque", "Lorsqu'"},
+		Then:            []string{"Alors", "Donc"},
+		And:             []string{"Et que", "Et qu'", "Et", "*"},
+		But:             []string{"Mais que", "Mais qu'", "Mais"},
+	},
+}
+
+func init() {
+	for _, l := range Languages {
+		l.sectionKeywords = l.buildSectionKeywords()
+		l.stepKeywords = l.buildStepKeywords()
+	}
+}
+
+// LanguageFor returns the keyword table for a language code, falling back to
+// English for an empty or unknown code.
+func LanguageFor(code string) *Language {
+	if lang, ok := Languages[code]; ok {
+		return lang
+	}
+	return Languages[DefaultLanguage]
+}
+
+var languageHeaderRe = regexp.MustCompile(`^#\s*language\s*:\s*(\S+)\s*$`)
+
+// languageHeader returns the code from a `# language: xx` comment line, if
+// the trimmed line is one.
+func languageHeader(trimmed string) (string, bool) {
+	m := languageHeaderRe.FindStringSubmatch(trimmed)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// SectionKeywords returns every block keyword with its trailing colon,
+// longest first so that e.g. "Scenario Outline:" wins over "Scenario:".
+// Callers must not modify the slice.
+func (l *Language) SectionKeywords() []string {
+	return l.sectionKeywords
+}
+
+func (l *Language) buildSectionKeywords() []string {
+	var kws []string
+	for _, group := range [][]string{l.Feature, l.Background, l.Rule, l.Scenario, l.ScenarioOutline, l.Examples} {
+		for _, kw := range group {
+			kws = append(kws, kw+":")
+		}
+	}
+	return longestFirst(kws)
+}
+
+// StepKeywords returns every step keyword, longest first. Callers must not
+// modify the slice.
+func (l *Language) StepKeywords() []string {
+	return l.stepKeywords
+}
+
+func (l *Language) buildStepKeywords() []string {
+	var kws []string
+	kws = append(kws, l.primaryStepKeywords()...)
+	kws = append(kws, l.continuationStepKeywords()...)
+	return longestFirst(kws)
+}
+
+// primaryStepKeywords start a new StepGroup.
+func (l *Language) primaryStepKeywords() []string {
+	var kws []string
+	kws = append(kws, l.Given...)
+	kws = append(kws, l.When...)
+	kws = append(kws, l.Then...)
+	return kws
+}
+
+// continuationStepKeywords extend the current StepGroup as an AltStep.
+func (l *Language) continuationStepKeywords() []string {
+	var kws []string
+	kws = append(kws, l.And...)
+	kws = append(kws, l.But...)
+	return kws
+}
+
+// cut reports whether trimmed starts with one of the given block keywords
+// followed by a colon, returning the trimmed text after the colon.
+func (l *Language) cut(trimmed string, keywords []string) (string, bool) {
+	for _, kw := range keywords {
+		if after, ok := strings.CutPrefix(trimmed, kw+":"); ok {
+			return strings.TrimSpace(after), true
+		}
+	}
+	return "", false
+}
+
+// is reports whether trimmed starts with one of the given block keywords.
+func (l *Language) is(trimmed string, keywords []string) bool {
+	_, ok := l.cut(trimmed, keywords)
+	return ok
+}
+
+// IsKeyword reports whether trimmed starts with any block keyword.
+func (l *Language) IsKeyword(trimmed string) bool {
+	for _, kw := range l.SectionKeywords() {
+		if strings.HasPrefix(trimmed, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScenario reports whether trimmed starts a Scenario or Scenario Outline.
+func (l *Language) isScenario(trimmed string) bool {
+	return l.is(trimmed, l.Scenario) || l.is(trimmed, l.ScenarioOutline)
+}
+
+func longestFirst(kws []string) []string {
+	sort.SliceStable(kws, func(i, j int) bool { return len(kws[i]) > len(kws[j]) })
+	return kws
+}
@@ -1,15 +1,18 @@
 package parser
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 var tagPattern = regexp.MustCompile(`@[^@\s]+`)
 
 // Parse parses a .ft file and returns a Document AST and any parse errors.
+// A leading byte order mark is ignored, as ParseCST keeps it aside.
 func Parse(filename string, content []byte) (*Document, []ParseError) {
-	lines := strings.Split(string(content), "\n")
+	lines := splitLines(content)
 	var errors []ParseError
 
 	doc := &Document{}
@@ -18,15 +21,24 @@ func Parse(filename string, content []byte) (*Document, []ParseError) {
 
 	i := 0
 
-	// Skip leading blanks and comments
+	// Skip leading blanks and comments, picking up a `# language:` header
+	lang := LanguageFor(DefaultLanguage)
 	for i < len(lines) {
 		trimmed := strings.TrimSpace(lines[i])
+		if code, ok := languageHeader(trimmed); ok {
+			if l, known := Languages[code]; known {
+				lang = l
+			} else {
+				errors = append(errors, ParseError{Line: i + 1, EndLine: i + 1, Column: columnOf(lines[i]), Message: fmt.Sprintf("Unknown language %q", code), Fatal: true})
+			}
+		}
 		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
 			i++
 			continue
 		}
 		break
 	}
+	doc.Language = lang.Code
 
 	// Collect feature-level tags
 	var featureTags []Tag
@@ -43,24 +55,11 @@ func Parse(filename string, content []byte) (*Document, []ParseError) {
 	// Look for Feature: line
 	if i < len(lines) {
 		trimmed := strings.TrimSpace(lines[i])
-		if after, ok := strings.CutPrefix(trimmed, "Feature:"); ok {
-			feature.Header.Name = strings.TrimSpace(after)
+		if name, ok := lang.cut(trimmed, lang.Feature); ok {
+			feature.Header.Name = name
 			feature.Header.Tags = featureTags
 			i++
-
-			// Scan description lines until keyword or tag
-			var descLines []string
-			for i < len(lines) {
-				trimmed := strings.TrimSpace(lines[i])
-				if isKeyword(trimmed) || isTagLine(trimmed) {
-					break
-				}
-				descLines = append(descLines, lines[i])
-				i++
-			}
-			if len(descLines) > 0 {
-				feature.Header.Description = strings.Join(descLines, "\n")
-			}
+			feature.Header.Description, i = scanDescription(lang, lines, i)
 		} else {
 			// No Feature: line — use filename without extension
 			name := filename
@@ -86,8 +85,10 @@ func Parse(filename string, content []byte) (*Document, []ParseError) {
 		feature.Header.Tags = featureTags
 	}
 
-	// Body loop
+	// Body loop. Once a Rule: has been seen, every following Background and
+	// scenario belongs to the most recent rule rather than the feature.
 	var pendingTags []Tag
+	var rule *Rule
 	for i < len(lines) {
 		trimmed := strings.TrimSpace(lines[i])
 
@@ -115,68 +116,58 @@ func Parse(filename string, content []byte) (*Document, []ParseError) {
 		}
 
 		// Background:
-		if strings.HasPrefix(trimmed, "Background:") {
+		if lang.is(trimmed, lang.Background) {
 			pendingTags = nil // Background doesn't get tags
-			bg := &Background{}
+			bg := &Background{Line: i + 1}
 			i++
-			i = consumeBlock(lines, i)
-			feature.Background = bg
+			start := i
+			i = consumeBlock(lang, lines, i)
+			bg.Description, bg.StepGroups = parseBody(lang, lines, start, i)
+			if rule != nil {
+				rule.Background = bg
+			} else {
+				feature.Background = bg
+			}
 			continue
 		}
 
-		// Scenario:
-		if strings.HasPrefix(trimmed, "Scenario:") {
-			scenarioLine := i + 1 // 1-based
-			name := strings.TrimSpace(strings.TrimPrefix(trimmed, "Scenario:"))
-			sd := ScenarioDefinition{
-				Tags:     pendingTags,
-				Scenario: Scenario{Name: name},
-				Line:     scenarioLine,
-			}
+		// Scenario: or Scenario Outline:
+		if lang.isScenario(trimmed) {
+			var sd ScenarioDefinition
+			sd, i = parseScenario(lang, lines, i, pendingTags)
 			pendingTags = nil
-			i++
-			// Consume until next Scenario:, Background:, tag line preceding Scenario:, or EOF
-			for i < len(lines) {
-				t := strings.TrimSpace(lines[i])
-				// Skip over doc strings entirely
-				if isDocStringDelimiter(t) {
-					i = skipDocString(lines, i)
-					continue
-				}
-				if strings.HasPrefix(t, "Scenario:") || strings.HasPrefix(t, "Background:") {
-					break
-				}
-				if strings.HasPrefix(t, "Scenario Outline:") || strings.HasPrefix(t, "Rule:") || strings.HasPrefix(t, "Examples:") {
-					break
-				}
-				if isTagLine(t) {
-					if tagPrecedesKeyword(lines, i) {
-						break
-					}
-				}
-				i++
+			if rule != nil {
+				rule.Scenarios = append(rule.Scenarios, sd)
+			} else {
+				feature.Scenarios = append(feature.Scenarios, sd)
 			}
-			feature.Scenarios = append(feature.Scenarios, sd)
 			continue
 		}
 
-		// Unsupported keywords
-		if strings.HasPrefix(trimmed, "Scenario Outline:") {
-			errors = append(errors, ParseError{Line: i + 1, Message: "Scenario Outline is not supported"})
-			i++
-			i = consumeBlock(lines, i)
-			continue
-		}
-		if strings.HasPrefix(trimmed, "Rule:") {
-			errors = append(errors, ParseError{Line: i + 1, Message: "Rule is not supported"})
+		// Rule:
+		if name, ok := lang.cut(trimmed, lang.Rule); ok {
+			feature.Rules = append(feature.Rules, Rule{
+				Tags: pendingTags,
+				Name: name,
+				Line: i + 1,
+			})
+			rule = &feature.Rules[len(feature.Rules)-1]
+			pendingTags = nil
 			i++
-			i = consumeBlock(lines, i)
+			rule.Description, i = scanDescription(lang, lines, i)
 			continue
 		}
-		if strings.HasPrefix(trimmed, "Examples:") {
-			errors = append(errors, ParseError{Line: i + 1, Message: "Examples is not supported"})
-			i++
-			i = consumeBlock(lines, i)
+
+		// Unsupported keywords
+		if lang.is(trimmed, lang.Examples) {
+			start := i
+			i = consumeBlock(lang, lines, i+1)
+			errors = append(errors, ParseError{
+				Line:    start + 1,
+				EndLine: lastNonBlank(lines, start, i) + 1,
+				Column:  columnOf(lines[start]),
+				Message: "Examples must belong to a Scenario Outline",
+			})
 			continue
 		}
 
@@ -187,6 +178,22 @@ func Parse(filename string, content []byte) (*Document, []ParseError) {
 	return doc, errors
 }
 
+// scanDescription collects the free-form description lines that follow a
+// Feature: or Rule: line, up to the next keyword or tag line. Returns the
+// description and the index of the first line after it.
+func scanDescription(lang *Language, lines []string, i int) (string, int) {
+	var descLines []string
+	for i < len(lines) {
+		trimmed := strings.TrimSpace(lines[i])
+		if lang.IsKeyword(trimmed) || isTagLine(trimmed) {
+			break
+		}
+		descLines = append(descLines, lines[i])
+		i++
+	}
+	return strings.Join(descLines, "\n"), i
+}
+
 func parseTags(line string) []Tag {
 	matches := tagPattern.FindAllString(line, -1)
 	var tags []Tag
@@ -200,15 +207,6 @@ func isTagLine(trimmed string) bool {
 	return strings.HasPrefix(trimmed, "@")
 }
 
-func isKeyword(trimmed string) bool {
-	return strings.HasPrefix(trimmed, "Feature:") ||
-		strings.HasPrefix(trimmed, "Background:") ||
-		strings.HasPrefix(trimmed, "Scenario:") ||
-		strings.HasPrefix(trimmed, "Scenario Outline:") ||
-		strings.HasPrefix(trimmed, "Rule:") ||
-		strings.HasPrefix(trimmed, "Examples:")
-}
-
 func isDocStringDelimiter(trimmed string) bool {
 	return strings.HasPrefix(trimmed, `"""`) || strings.HasPrefix(trimmed, "```")
 }
@@ -233,14 +231,14 @@ func skipDocString(lines []string, i int) int {
 
 // consumeBlock advances past content lines, skipping over doc strings,
 // until the next keyword, tag line, or EOF.
-func consumeBlock(lines []string, i int) int {
+func consumeBlock(lang *Language, lines []string, i int) int {
 	for i < len(lines) {
 		t := strings.TrimSpace(lines[i])
 		if isDocStringDelimiter(t) {
 			i = skipDocString(lines, i)
 			continue
 		}
-		if isKeyword(t) || isTagLine(t) {
+		if lang.IsKeyword(t) || isTagLine(t) {
 			break
 		}
 		i++
@@ -248,8 +246,10 @@ func consumeBlock(lines []string, i int) int {
 	return i
 }
 
-// tagPrecedesKeyword checks if a tag line at index i is followed by a Scenario: or keyword line.
-func tagPrecedesKeyword(lines []string, i int) bool {
+// keywordAfterTags returns the trimmed keyword line that the tag line at
+// index i (and any tag, blank or comment lines after it) attaches to, or ""
+// if the tags are followed by something other than a keyword.
+func keywordAfterTags(lang *Language, lines []string, i int) string {
 	for j := i + 1; j < len(lines); j++ {
 		t := strings.TrimSpace(lines[j])
 		if t == "" || strings.HasPrefix(t, "#") {
@@ -258,11 +258,155 @@ func tagPrecedesKeyword(lines []string, i int) bool {
 		if strings.HasPrefix(t, "@") {
 			continue
 		}
-		return strings.HasPrefix(t, "Scenario:") ||
-			strings.HasPrefix(t, "Background:") ||
-			strings.HasPrefix(t, "Scenario Outline:") ||
-			strings.HasPrefix(t, "Rule:") ||
-			strings.HasPrefix(t, "Examples:")
+		if lang.IsKeyword(t) {
+			return t
+		}
+		return ""
+	}
+	return ""
+}
+
+// parseScenario consumes a Scenario: or Scenario Outline: block whose
+// keyword line is at index i, including an outline's Examples: tables.
+// Returns the definition and the index of the first line after the block.
+func parseScenario(lang *Language, lines []string, i int, tags []Tag) (ScenarioDefinition, int) {
+	trimmed := strings.TrimSpace(lines[i])
+	sd := ScenarioDefinition{Tags: tags, Line: i + 1}
+	if name, ok := lang.cut(trimmed, lang.ScenarioOutline); ok {
+		sd.Scenario.Outline = true
+		sd.Scenario.Name = name
+	} else {
+		sd.Scenario.Name, _ = lang.cut(trimmed, lang.Scenario)
+	}
+	i++
+	bodyStart := i
+	bodyEnd := -1 // first line of the first Examples block, if any
+
+	var exampleTags []Tag
+	var examplesDesc []string
+	for i < len(lines) {
+		t := strings.TrimSpace(lines[i])
+		// Skip over doc strings entirely
+		if isDocStringDelimiter(t) {
+			i = skipDocString(lines, i)
+			continue
+		}
+		if lang.isScenario(t) || lang.is(t, lang.Background) || lang.is(t, lang.Rule) {
+			break
+		}
+		if name, ok := lang.cut(t, lang.Examples); ok {
+			if !sd.Scenario.Outline {
+				break
+			}
+			if bodyEnd == -1 {
+				bodyEnd = i
+			}
+			sd.Scenario.Examples = append(sd.Scenario.Examples, Examples{
+				Tags: exampleTags,
+				Name: name,
+				Line: i + 1,
+			})
+			exampleTags = nil
+			examplesDesc = nil
+			i++
+			continue
+		}
+		if isTagLine(t) {
+			next := keywordAfterTags(lang, lines, i)
+			if sd.Scenario.Outline && lang.is(next, lang.Examples) {
+				if bodyEnd == -1 {
+					bodyEnd = i
+				}
+				exampleTags = append(exampleTags, parseTags(t)...)
+				i++
+				continue
+			}
+			if next != "" {
+				break
+			}
+		}
+		if n := len(sd.Scenario.Examples); n > 0 {
+			ex := &sd.Scenario.Examples[n-1]
+			switch {
+			case isTableRow(t):
+				cells := parseTableRow(t)
+				if ex.Table == nil {
+					ex.Table = &DataTable{HeaderRow: cells}
+				} else {
+					ex.Table.Rows = append(ex.Table.Rows, cells)
+				}
+			case ex.Table == nil && t != "" && !strings.HasPrefix(t, "#"):
+				examplesDesc = append(examplesDesc, t)
+				ex.Description = strings.Join(examplesDesc, "\n")
+			}
+		}
+		i++
+	}
+	if bodyEnd == -1 {
+		bodyEnd = i
+	}
+	sd.Scenario.Description, sd.Scenario.StepGroups = parseBody(lang, lines, bodyStart, bodyEnd)
+	return sd, i
+}
+
+func isTableRow(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "|")
+}
+
+// parseTableRow splits a |-delimited table row into trimmed cells,
+// honoring the \\, \| and \n escapes.
+func parseTableRow(trimmed string) []string {
+	var cells []string
+	var cell strings.Builder
+	started := false
+	runes := []rune(trimmed)
+	for k := 0; k < len(runes); k++ {
+		r := runes[k]
+		switch {
+		case r == '\\' && k+1 < len(runes):
+			k++
+			switch runes[k] {
+			case 'n':
+				cell.WriteRune('\n')
+			case '|', '\\':
+				cell.WriteRune(runes[k])
+			default:
+				cell.WriteRune('\\')
+				cell.WriteRune(runes[k])
+			}
+		case r == '|':
+			if started {
+				cells = append(cells, strings.TrimSpace(cell.String()))
+			}
+			cell.Reset()
+			started = true
+		default:
+			cell.WriteRune(r)
+		}
+	}
+	return cells
+}
+
+// lastNonBlank returns the index of the last non-blank line in
+// lines[start:end], or start if they're all blank.
+func lastNonBlank(lines []string, start, end int) int {
+	for j := end - 1; j > start; j-- {
+		if strings.TrimSpace(lines[j]) != "" {
+			return j
+		}
 	}
-	return false
+	return start
+}
+
+// columnOf returns the 1-based column of the first non-blank character of
+// line, where a diagnostic about the line points.
+func columnOf(line string) int {
+	return utf8.RuneCountInString(line) - utf8.RuneCountInString(strings.TrimLeft(line, " \t")) + 1
+}
+
+// splitLines splits content into lines without its byte order mark. The
+// lines of a CRLF file keep their \r, which callers trim along with other
+// trailing whitespace.
+func splitLines(content []byte) []string {
+	return strings.Split(strings.TrimPrefix(string(content), bom), "\n")
 }
@@ -66,35 +66,150 @@ func TestParse_ExistingFtTags(t *testing.T) {
 	assert.Equal(t, "@ft:1", doc.Feature.Scenarios[0].Tags[0].Name)
 }
 
-func TestParse_ScenarioOutlineError(t *testing.T) {
+func TestParse_ScenarioOutline(t *testing.T) {
 	content := []byte(`Feature: Login
-  Scenario Outline: User logs in
-    Given a user
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+    Then  they see <page>
+
+    Examples:
+      | role  | page      |
+      | admin | settings  |
+      | guest | dashboard |
 `)
-	_, errors := Parse("login.ft", content)
-	require.Len(t, errors, 1)
-	assert.Equal(t, "Scenario Outline is not supported", errors[0].Message)
-	assert.Equal(t, 2, errors[0].Line)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	require.Len(t, doc.Feature.Scenarios, 1)
+	sc := doc.Feature.Scenarios[0].Scenario
+	assert.True(t, sc.Outline)
+	assert.Equal(t, "User logs in as <role>", sc.Name)
+	require.Len(t, sc.Examples, 1)
+	assert.Equal(t, 6, sc.Examples[0].Line)
+	require.NotNil(t, sc.Examples[0].Table)
+	assert.Equal(t, []string{"role", "page"}, sc.Examples[0].Table.HeaderRow)
+	assert.Equal(t, [][]string{{"admin", "settings"}, {"guest", "dashboard"}}, sc.Examples[0].Table.Rows)
 }
 
-func TestParse_RuleError(t *testing.T) {
+func TestParse_ScenarioOutlineMultipleTaggedExamples(t *testing.T) {
 	content := []byte(`Feature: Login
-  Rule: Business rule
-    Scenario: Test
+  @ft:3
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    @fast
+    Examples: Staff
+      Staff accounts only.
+      | role  |
+      | admin |
+
+    @slow
+    Examples: Visitors
+      | role  |
+      | guest |
+
+  @ft:4
+  Scenario: User logs out
+    Given a user
 `)
-	_, errors := Parse("login.ft", content)
-	require.Len(t, errors, 1)
-	assert.Equal(t, "Rule is not supported", errors[0].Message)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	require.Len(t, doc.Feature.Scenarios, 2)
+
+	outline := doc.Feature.Scenarios[0]
+	assert.Equal(t, []Tag{{Name: "@ft:3"}}, outline.Tags)
+	require.Len(t, outline.Scenario.Examples, 2)
+	assert.Equal(t, "Staff", outline.Scenario.Examples[0].Name)
+	assert.Equal(t, "Staff accounts only.", outline.Scenario.Examples[0].Description)
+	assert.Equal(t, []Tag{{Name: "@fast"}}, outline.Scenario.Examples[0].Tags)
+	assert.Equal(t, "Visitors", outline.Scenario.Examples[1].Name)
+	assert.Equal(t, []Tag{{Name: "@slow"}}, outline.Scenario.Examples[1].Tags)
+	assert.Equal(t, [][]string{{"guest"}}, outline.Scenario.Examples[1].Table.Rows)
+
+	assert.False(t, doc.Feature.Scenarios[1].Scenario.Outline)
+	assert.Equal(t, []Tag{{Name: "@ft:4"}}, doc.Feature.Scenarios[1].Tags)
 }
 
-func TestParse_ExamplesError(t *testing.T) {
+func TestParse_TableRowEscapes(t *testing.T) {
+	assert.Equal(t, []string{"a|b", `c\d`, "e\nf", ""}, parseTableRow(`| a\|b | c\\d | e\nf ||`))
+}
+
+func TestParse_Rule(t *testing.T) {
+	content := []byte(`Feature: Refunds
+  Background:
+    Given a customer
+
+  Scenario: Refund page loads
+    Given the refund page
+
+  @billing
+  Rule: Refunds need a receipt
+    Purchases without a receipt are store credit only.
+
+    Background:
+      Given a purchase
+
+    Scenario: Refund with receipt
+      Given a receipt
+
+    Scenario: Refund without receipt
+      Given no receipt
+
+  Rule: Refunds expire
+    Scenario: Refund after 30 days
+      Given an old purchase
+`)
+	doc, errors := Parse("refunds.ft", content)
+	require.Empty(t, errors)
+	require.NotNil(t, doc.Feature.Background)
+	assert.Equal(t, 2, doc.Feature.Background.Line)
+	require.Len(t, doc.Feature.Scenarios, 1)
+	assert.Equal(t, "Refund page loads", doc.Feature.Scenarios[0].Scenario.Name)
+
+	require.Len(t, doc.Feature.Rules, 2)
+	first := doc.Feature.Rules[0]
+	assert.Equal(t, "Refunds need a receipt", first.Name)
+	assert.Equal(t, 9, first.Line)
+	assert.Equal(t, []Tag{{Name: "@billing"}}, first.Tags)
+	assert.Contains(t, first.Description, "store credit only")
+	require.NotNil(t, first.Background)
+	assert.Equal(t, 12, first.Background.Line)
+	require.Len(t, first.Scenarios, 2)
+	assert.Equal(t, "Refund with receipt", first.Scenarios[0].Scenario.Name)
+	assert.Equal(t, "Refund without receipt", first.Scenarios[1].Scenario.Name)
+
+	second := doc.Feature.Rules[1]
+	assert.Equal(t, "Refunds expire", second.Name)
+	assert.Nil(t, second.Background)
+	require.Len(t, second.Scenarios, 1)
+	assert.Equal(t, "Refund after 30 days", second.Scenarios[0].Scenario.Name)
+}
+
+func TestParse_ExamplesOutsideOutlineError(t *testing.T) {
 	content := []byte(`Feature: Login
   Examples: Table
     | a |
 `)
 	_, errors := Parse("login.ft", content)
 	require.Len(t, errors, 1)
-	assert.Equal(t, "Examples is not supported", errors[0].Message)
+	assert.Equal(t, "Examples must belong to a Scenario Outline", errors[0].Message)
+}
+
+func TestParse_ExamplesUnderPlainScenarioError(t *testing.T) {
+	content := []byte(`Feature: Login
+  Scenario: User logs in
+    Given a user
+
+    Examples:
+      | a |
+`)
+	doc, errors := Parse("login.ft", content)
+	require.Len(t, errors, 1)
+	assert.Equal(t, 5, errors[0].Line)
+	assert.Equal(t, 6, errors[0].EndLine)
+	assert.Equal(t, 5, errors[0].Column)
+	assert.False(t, errors[0].Fatal)
+	require.Len(t, doc.Feature.Scenarios, 1)
+	assert.Empty(t, doc.Feature.Scenarios[0].Scenario.Examples)
 }
 
 func TestParse_NoFeatureLine(t *testing.T) {
@@ -224,3 +339,311 @@ func TestParse_MultipleScenarios_WithDocStrings(t *testing.T) {
 	assert.Equal(t, "First", doc.Feature.Scenarios[0].Scenario.Name)
 	assert.Equal(t, "Second", doc.Feature.Scenarios[1].Scenario.Name)
 }
+
+func TestParse_StepGroups(t *testing.T) {
+	content := []byte(`Feature: Login
+  Scenario: User logs in
+    A user with an account signs in.
+
+    Given a user
+    And   a password
+    When  they log in
+    But   the network is slow
+    Then  they see the dashboard
+    * nothing else happens
+`)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	sc := doc.Feature.Scenarios[0].Scenario
+	assert.Equal(t, "A user with an account signs in.", sc.Description)
+	require.Len(t, sc.StepGroups, 3)
+
+	assert.Equal(t, Step{Keyword: "Given", Text: "a user", Line: 5}, sc.StepGroups[0].Step)
+	assert.Equal(t, []Step{{Keyword: "And", Text: "a password", Line: 6}}, sc.StepGroups[0].AltSteps)
+
+	assert.Equal(t, "When", sc.StepGroups[1].Step.Keyword)
+	assert.Equal(t, "they log in", sc.StepGroups[1].Step.Text)
+	require.Len(t, sc.StepGroups[1].AltSteps, 1)
+	assert.Equal(t, "But", sc.StepGroups[1].AltSteps[0].Keyword)
+
+	assert.Equal(t, "Then", sc.StepGroups[2].Step.Keyword)
+	require.Len(t, sc.StepGroups[2].AltSteps, 1)
+	assert.Equal(t, Step{Keyword: "*", Text: "nothing else happens", Line: 10}, sc.StepGroups[2].AltSteps[0])
+}
+
+func TestParse_LeadingContinuationStartsGroup(t *testing.T) {
+	content := []byte(`Feature: Login
+  Scenario: Starts with a star
+    * a user
+    And a password
+`)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	groups := doc.Feature.Scenarios[0].Scenario.StepGroups
+	require.Len(t, groups, 1)
+	assert.Equal(t, "*", groups[0].Step.Keyword)
+	require.Len(t, groups[0].AltSteps, 1)
+	assert.Equal(t, "And", groups[0].AltSteps[0].Keyword)
+}
+
+func TestParse_StepDocString(t *testing.T) {
+	content := []byte("Feature: API\n" +
+		"  Scenario: Posts JSON\n" +
+		"    Given the request body:\n" +
+		"      \"\"\"json\n" +
+		"      {\n" +
+		"        \"name\": \"a \\\"quoted\\\" value\"\n" +
+		"      }\n" +
+		"      \"\"\"\n" +
+		"    When  it is sent\n")
+	doc, errors := Parse("api.ft", content)
+	require.Empty(t, errors)
+	groups := doc.Feature.Scenarios[0].Scenario.StepGroups
+	require.Len(t, groups, 2)
+	arg := groups[0].Step.Argument
+	require.NotNil(t, arg)
+	require.NotNil(t, arg.DocString)
+	assert.Nil(t, arg.DataTable)
+	assert.Equal(t, "json", arg.DocString.MediaType)
+	assert.Equal(t, "{\n  \"name\": \"a \"quoted\" value\"\n}", arg.DocString.Content)
+	assert.Nil(t, groups[1].Step.Argument)
+}
+
+func TestParse_StepDocStringWithBackticks(t *testing.T) {
+	content := []byte("Feature: Test\n  Scenario: Has code block\n    Given content:\n      ```\n      Scenario: Not real\n      ```\n")
+	doc, errors := Parse("test.ft", content)
+	require.Empty(t, errors)
+	arg := doc.Feature.Scenarios[0].Scenario.StepGroups[0].Step.Argument
+	require.NotNil(t, arg)
+	assert.Equal(t, "", arg.DocString.MediaType)
+	assert.Equal(t, "Scenario: Not real", arg.DocString.Content)
+}
+
+func TestParse_StepDataTable(t *testing.T) {
+	content := []byte(`Feature: Users
+  Scenario: Many users
+    Given these users:
+      | name  | role  |
+      | alice | admin |
+      | bob   |       |
+    Then  there are 2 users
+`)
+	doc, errors := Parse("users.ft", content)
+	require.Empty(t, errors)
+	groups := doc.Feature.Scenarios[0].Scenario.StepGroups
+	require.Len(t, groups, 2)
+	table := groups[0].Step.Argument.DataTable
+	require.NotNil(t, table)
+	assert.Equal(t, []string{"name", "role"}, table.HeaderRow)
+	assert.Equal(t, [][]string{{"alice", "admin"}, {"bob", ""}}, table.Rows)
+}
+
+func TestParse_BackgroundSteps(t *testing.T) {
+	content := []byte(`Feature: Login
+  Background: Shared setup
+    Given a registered user
+    And   the login page
+
+  Scenario: User logs in
+    When  they log in
+`)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	bg := doc.Feature.Background
+	require.NotNil(t, bg)
+	require.Len(t, bg.StepGroups, 1)
+	assert.Equal(t, "a registered user", bg.StepGroups[0].Step.Text)
+	require.Len(t, bg.StepGroups[0].AltSteps, 1)
+	assert.Equal(t, "the login page", bg.StepGroups[0].AltSteps[0].Text)
+
+	require.Len(t, doc.Feature.Scenarios[0].Scenario.StepGroups, 1)
+	assert.Equal(t, "When", doc.Feature.Scenarios[0].Scenario.StepGroups[0].Step.Keyword)
+}
+
+func TestParse_OutlineStepsStopAtExamples(t *testing.T) {
+	content := []byte(`Feature: Login
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    @fast
+    Examples:
+      | role  |
+      | admin |
+`)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	sc := doc.Feature.Scenarios[0].Scenario
+	require.Len(t, sc.StepGroups, 1)
+	assert.Nil(t, sc.StepGroups[0].Step.Argument)
+	require.Len(t, sc.Examples, 1)
+	assert.Equal(t, [][]string{{"admin"}}, sc.Examples[0].Table.Rows)
+}
+
+func TestParse_GermanKeywords(t *testing.T) {
+	content := []byte(`# language: de
+Funktionalität: Anmeldung
+  Grundlage:
+    Angenommen ein registrierter Benutzer
+
+  @smoke
+  Szenario: Erfolgreiche Anmeldung
+    Wenn er sich anmeldet
+    Und  das Passwort stimmt
+    Dann sieht er das Dashboard
+
+  Regel: Gesperrte Konten
+    Szenariogrundriss: Gesperrt nach <n> Versuchen
+      Gegeben sei ein Konto mit <n> Fehlversuchen
+
+      Beispiele:
+        | n |
+        | 3 |
+`)
+	doc, errors := Parse("anmeldung.ft", content)
+	require.Empty(t, errors)
+	assert.Equal(t, "de", doc.Language)
+	assert.Equal(t, "Anmeldung", doc.Feature.Header.Name)
+	require.NotNil(t, doc.Feature.Background)
+	require.Len(t, doc.Feature.Background.StepGroups, 1)
+	assert.Equal(t, "Angenommen", doc.Feature.Background.StepGroups[0].Step.Keyword)
+
+	require.Len(t, doc.Feature.Scenarios, 1)
+	sc := doc.Feature.Scenarios[0]
+	assert.Equal(t, "Erfolgreiche Anmeldung", sc.Scenario.Name)
+	assert.Equal(t, []Tag{{Name: "@smoke"}}, sc.Tags)
+	require.Len(t, sc.Scenario.StepGroups, 2)
+	assert.Equal(t, "Wenn", sc.Scenario.StepGroups[0].Step.Keyword)
+	require.Len(t, sc.Scenario.StepGroups[0].AltSteps, 1)
+	assert.Equal(t, "Und", sc.Scenario.StepGroups[0].AltSteps[0].Keyword)
+	assert.Equal(t, "Dann", sc.Scenario.StepGroups[1].Step.Keyword)
+
+	require.Len(t, doc.Feature.Rules, 1)
+	rule := doc.Feature.Rules[0]
+	assert.Equal(t, "Gesperrte Konten", rule.Name)
+	require.Len(t, rule.Scenarios, 1)
+	outline := rule.Scenarios[0].Scenario
+	assert.True(t, outline.Outline)
+	assert.Equal(t, "Gesperrt nach <n> Versuchen", outline.Name)
+	require.Len(t, outline.StepGroups, 1)
+	assert.Equal(t, "Gegeben sei", outline.StepGroups[0].Step.Keyword)
+	assert.Equal(t, "ein Konto mit <n> Fehlversuchen", outline.StepGroups[0].Step.Text)
+	require.Len(t, outline.Examples, 1)
+	assert.Equal(t, [][]string{{"3"}}, outline.Examples[0].Table.Rows)
+}
+
+func TestParse_SpanishKeywords(t *testing.T) {
+	content := []byte(`# language: es
+Característica: Reembolsos
+  Antecedentes:
+    Dado un cliente
+
+  Escenario: Reembolso con recibo
+    Cuando pide un reembolso
+    Y    tiene el recibo
+    Entonces recibe el dinero
+    Pero no recibe puntos
+`)
+	doc, errors := Parse("reembolsos.ft", content)
+	require.Empty(t, errors)
+	assert.Equal(t, "es", doc.Language)
+	assert.Equal(t, "Reembolsos", doc.Feature.Header.Name)
+	require.NotNil(t, doc.Feature.Background)
+	require.Len(t, doc.Feature.Scenarios, 1)
+	sc := doc.Feature.Scenarios[0].Scenario
+	assert.Equal(t, "Reembolso con recibo", sc.Name)
+	require.Len(t, sc.StepGroups, 2)
+	assert.Equal(t, "Cuando", sc.StepGroups[0].Step.Keyword)
+	assert.Equal(t, "Y", sc.StepGroups[0].AltSteps[0].Keyword)
+	assert.Equal(t, "tiene el recibo", sc.StepGroups[0].AltSteps[0].Text)
+	assert.Equal(t, "Entonces", sc.StepGroups[1].Step.Keyword)
+	assert.Equal(t, "Pero", sc.StepGroups[1].AltSteps[0].Keyword)
+}
+
+func TestParse_FrenchElidedStepKeyword(t *testing.T) {
+	content := []byte(`# language: fr
+Fonctionnalité: Connexion
+  Scénario: Connexion réussie
+    Lorsqu'il se connecte
+    Alors il voit le tableau de bord
+`)
+	doc, errors := Parse("connexion.ft", content)
+	require.Empty(t, errors)
+	require.Len(t, doc.Feature.Scenarios, 1)
+	sc := doc.Feature.Scenarios[0].Scenario
+	require.Len(t, sc.StepGroups, 2)
+	assert.Equal(t, "Lorsqu'", sc.StepGroups[0].Step.Keyword)
+	assert.Equal(t, "il se connecte", sc.StepGroups[0].Step.Text)
+}
+
+func TestParse_EnglishSynonyms(t *testing.T) {
+	content := []byte(`Feature: Login
+  Example: User logs in
+    Given a user
+
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    Scenarios:
+      | role  |
+      | admin |
+`)
+	doc, errors := Parse("login.ft", content)
+	require.Empty(t, errors)
+	require.Len(t, doc.Feature.Scenarios, 2)
+	assert.Equal(t, "User logs in", doc.Feature.Scenarios[0].Scenario.Name)
+	outline := doc.Feature.Scenarios[1].Scenario
+	require.Len(t, outline.Examples, 1)
+	assert.Equal(t, [][]string{{"admin"}}, outline.Examples[0].Table.Rows)
+}
+
+func TestLanguage_IsKeywordDoesNotAllocate(t *testing.T) {
+	lang := LanguageFor("fr")
+	allocs := testing.AllocsPerRun(100, func() {
+		lang.IsKeyword("Plan du scénario: Connexion")
+		lang.IsKeyword("Alors il voit le tableau de bord")
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestParse_EnglishKeywordsIgnoredInOtherLanguage(t *testing.T) {
+	content := []byte(`# language: de
+Funktionalität: Anmeldung
+  Scenario: Not a scenario in German
+    Given not a step
+`)
+	doc, errors := Parse("anmeldung.ft", content)
+	require.Empty(t, errors)
+	assert.Empty(t, doc.Feature.Scenarios)
+	assert.Contains(t, doc.Feature.Header.Description, "Scenario: Not a scenario in German")
+}
+
+func TestParse_DefaultsToEnglish(t *testing.T) {
+	doc, errors := Parse("login.ft", []byte("# a comment\nFeature: Login\n"))
+	require.Empty(t, errors)
+	assert.Equal(t, "en", doc.Language)
+	assert.Equal(t, "Login", doc.Feature.Header.Name)
+}
+
+func TestParse_IgnoresBOM(t *testing.T) {
+	doc, errors := Parse("login.ft", []byte("\uFEFFFeature: Login\r\n  Scenario: User logs in\r\n    Given a user\r\n"))
+	require.Empty(t, errors)
+	assert.Equal(t, "Login", doc.Feature.Header.Name)
+	require.Len(t, doc.Feature.Scenarios, 1)
+	assert.Equal(t, "User logs in", doc.Feature.Scenarios[0].Scenario.Name)
+
+	doc, errors = Parse("anmeldung.ft", []byte("\uFEFF# language: de\nFunktionalität: Anmeldung\n"))
+	require.Empty(t, errors)
+	assert.Equal(t, "de", doc.Language)
+	assert.Equal(t, "Anmeldung", doc.Feature.Header.Name)
+}
+
+func TestParse_UnknownLanguage(t *testing.T) {
+	doc, errors := Parse("login.ft", []byte("# language: xx\nFeature: Login\n"))
+	require.Len(t, errors, 1)
+	assert.Equal(t, 1, errors[0].Line)
+	assert.Equal(t, 1, errors[0].Column)
+	assert.True(t, errors[0].Fatal)
+	assert.Equal(t, `Unknown language "xx"`, errors[0].Message)
+	assert.Equal(t, "en", doc.Language)
+	assert.Equal(t, "Login", doc.Feature.Header.Name)
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseBody parses the lines of a Background or Scenario body — everything
+// between its keyword line (exclusive) and end (exclusive) — into a
+// description and step groups. Description lines are the free text before
+// the first step. Doc strings and data tables attach to the step before them.
+func parseBody(lang *Language, lines []string, start, end int) (string, []StepGroup) {
+	var descLines []string
+	var groups []StepGroup
+	var last *Step
+
+	for i := start; i < end; {
+		trimmed := strings.TrimSpace(lines[i])
+
+		if isDocStringDelimiter(trimmed) {
+			next := skipDocString(lines, i)
+			if last != nil && last.Argument == nil {
+				last.Argument = &StepArgument{DocString: parseDocString(lines[i:min(next, end)])}
+			}
+			i = next
+			continue
+		}
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || isTagLine(trimmed) {
+			i++
+			continue
+		}
+
+		if isTableRow(trimmed) {
+			if last != nil && (last.Argument == nil || last.Argument.DataTable != nil) {
+				if last.Argument == nil {
+					last.Argument = &StepArgument{DataTable: &DataTable{HeaderRow: parseTableRow(trimmed)}}
+				} else {
+					last.Argument.DataTable.Rows = append(last.Argument.DataTable.Rows, parseTableRow(trimmed))
+				}
+			}
+			i++
+			continue
+		}
+
+		if keyword, text, ok := splitStep(lang, trimmed); ok {
+			step := Step{Keyword: keyword, Text: text, Line: i + 1}
+			if isContinuation(lang, keyword) && len(groups) > 0 {
+				g := &groups[len(groups)-1]
+				g.AltSteps = append(g.AltSteps, step)
+				last = &g.AltSteps[len(g.AltSteps)-1]
+			} else {
+				groups = append(groups, StepGroup{Step: step})
+				last = &groups[len(groups)-1].Step
+			}
+			i++
+			continue
+		}
+
+		// Free text before the first step is the block's description;
+		// anything after it is ignored.
+		if len(groups) == 0 {
+			descLines = append(descLines, trimmed)
+		}
+		i++
+	}
+
+	return strings.Join(descLines, "\n"), groups
+}
+
+// splitStep splits a trimmed line into its step keyword and text, e.g.
+// "When  they log in" → ("When", "they log in"). Keyword padding is dropped.
+func splitStep(lang *Language, trimmed string) (keyword, text string, ok bool) {
+	for _, kw := range lang.StepKeywords() {
+		if rest, found := cutKeyword(trimmed, kw); found {
+			return kw, rest, true
+		}
+	}
+	return "", "", false
+}
+
+// cutKeyword reports whether trimmed starts with kw as a whole word,
+// returning the rest of the line with surrounding space removed. Elided
+// keywords such as French "Lorsqu'" need no space after them.
+func cutKeyword(trimmed, kw string) (string, bool) {
+	rest, found := strings.CutPrefix(trimmed, kw)
+	if !found {
+		return "", false
+	}
+	if strings.HasSuffix(kw, "'") {
+		return strings.TrimSpace(rest), true
+	}
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+func isContinuation(lang *Language, keyword string) bool {
+	for _, kw := range lang.continuationStepKeywords() {
+		if kw == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDocString builds a DocString from its lines, starting with the
+// opening delimiter and, if the doc string is closed, ending with the
+// closing one. Content lines have the opening delimiter's indentation
+// stripped, and \" and \\ are unescaped.
+func parseDocString(block []string) *DocString {
+	opener := block[0]
+	trimmedOpener := strings.TrimSpace(opener)
+	delimiter := `"""`
+	if strings.HasPrefix(trimmedOpener, "```") {
+		delimiter = "```"
+	}
+	indent := opener[:len(opener)-len(strings.TrimLeft(opener, " \t"))]
+
+	body := block[1:]
+	if len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == delimiter {
+		body = body[:len(body)-1]
+	}
+
+	unescape := strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+	content := make([]string, len(body))
+	for k, l := range body {
+		content[k] = unescape.Replace(strings.TrimPrefix(l, indent))
+	}
+
+	return &DocString{
+		MediaType: strings.TrimSpace(strings.TrimPrefix(trimmedOpener, delimiter)),
+		Content:   strings.Join(content, "\n"),
+	}
+}
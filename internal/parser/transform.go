@@ -8,16 +8,30 @@ import (
 type ParsedFile struct {
 	Name      string
 	Scenarios []ParsedScenario
+	Skipped   []ParsedScenario // scenarios overlapping a ParseError's region
 	Errors    []ParseError
 }
 
+// Fatal reports whether any error makes the whole file untrustworthy.
+func (pf *ParsedFile) Fatal() bool {
+	for _, e := range pf.Errors {
+		if e.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
 // ParsedScenario represents a single scenario extracted from a .ft file.
 type ParsedScenario struct {
 	Name      string   // from Scenario: line
 	FtTag     string   // just the ID portion, e.g. "1"
 	OtherTags []string // non-@ft tags
-	Content   string   // raw text from Scenario: line to end of scenario
+	Content   string   // raw text from Scenario: line to end of scenario, including any Examples: tables
 	Line      int      // 1-based line number of Scenario: line
+	EndLine   int      // 1-based line number of the last line of Content
+	Outline   bool     // true for a Scenario Outline
+	Rule      string   // name of the enclosing Rule:, if any
 }
 
 // Transform converts a Layer 1 Document into a Layer 2 ParsedFile.
@@ -36,12 +50,41 @@ func Transform(doc *Document, filename string, content []byte, errors []ParseErr
 		return pf
 	}
 
-	lines := strings.Split(string(content), "\n")
+	lines := splitLines(content)
 
+	// Every Scenario:, Rule: and Background: line bounds the content of the
+	// scenario before it.
+	var blockStarts []int
+	type ruledScenario struct {
+		sd   ScenarioDefinition
+		rule string
+	}
+	var all []ruledScenario
+	if doc.Feature.Background != nil {
+		blockStarts = append(blockStarts, doc.Feature.Background.Line)
+	}
 	for _, sd := range doc.Feature.Scenarios {
+		blockStarts = append(blockStarts, sd.Line)
+		all = append(all, ruledScenario{sd: sd})
+	}
+	for _, r := range doc.Feature.Rules {
+		blockStarts = append(blockStarts, r.Line)
+		if r.Background != nil {
+			blockStarts = append(blockStarts, r.Background.Line)
+		}
+		for _, sd := range r.Scenarios {
+			blockStarts = append(blockStarts, sd.Line)
+			all = append(all, ruledScenario{sd: sd, rule: r.Name})
+		}
+	}
+
+	for _, rs := range all {
+		sd := rs.sd
 		ps := ParsedScenario{
-			Name: sd.Scenario.Name,
-			Line: sd.Line,
+			Name:    sd.Scenario.Name,
+			Line:    sd.Line,
+			Outline: sd.Scenario.Outline,
+			Rule:    rs.rule,
 		}
 
 		// Partition tags into FtTag vs OtherTags
@@ -57,12 +100,12 @@ func Transform(doc *Document, filename string, content []byte, errors []ParseErr
 		startLine := sd.Line - 1 // 0-based
 		endLine := len(lines)
 
-		// Find the next scenario's start line or use end of file
-		for _, other := range doc.Feature.Scenarios {
-			if other.Line > sd.Line && other.Line-1 < endLine {
-				// The content ends before the next scenario's tags or Scenario: line
-				candidateEnd := other.Line - 1 // 0-based index of next Scenario: line
-				// Walk back to exclude tag lines and blank lines before the next scenario
+		// Find the next block's start line or use end of file
+		for _, other := range blockStarts {
+			if other > sd.Line && other-1 < endLine {
+				// The content ends before the next block's tags or keyword line
+				candidateEnd := other - 1 // 0-based index of next keyword line
+				// Walk back to exclude tag lines and blank lines before the next block
 				for candidateEnd > startLine {
 					t := strings.TrimSpace(lines[candidateEnd-1])
 					if t == "" || strings.HasPrefix(t, "@") || strings.HasPrefix(t, "#") {
@@ -77,11 +120,6 @@ func Transform(doc *Document, filename string, content []byte, errors []ParseErr
 			}
 		}
 
-		// Also check Background position
-		if doc.Feature.Background != nil {
-			// Background doesn't have a Line field in our AST, so we skip this check
-		}
-
 		// Trim trailing blank lines
 		for endLine > startLine && strings.TrimSpace(lines[endLine-1]) == "" {
 			endLine--
@@ -91,13 +129,29 @@ func Transform(doc *Document, filename string, content []byte, errors []ParseErr
 			contentLines := lines[startLine:endLine]
 			ps.Content = strings.Join(contentLines, "\n")
 		}
+		ps.EndLine = max(endLine, sd.Line)
 
+		if pf.Fatal() || overlapsError(ps, errors) {
+			pf.Skipped = append(pf.Skipped, ps)
+			continue
+		}
 		pf.Scenarios = append(pf.Scenarios, ps)
 	}
 
 	return pf
 }
 
+// overlapsError reports whether the scenario's lines overlap the broken
+// region of any error.
+func overlapsError(ps ParsedScenario, errors []ParseError) bool {
+	for _, e := range errors {
+		if e.Line <= ps.EndLine && max(e.EndLine, e.Line) >= ps.Line {
+			return true
+		}
+	}
+	return false
+}
+
 func filenameWithoutExt(filename string) string {
 	name := filename
 	if idx := strings.LastIndex(name, "/"); idx >= 0 {
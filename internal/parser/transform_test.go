@@ -93,14 +93,45 @@ func TestTransform_LineNumbers(t *testing.T) {
 
 func TestTransform_Errors(t *testing.T) {
 	content := []byte(`Feature: Login
-  Scenario Outline: User logs in
-    Given a user
+  Examples: Table
+    | a |
 `)
 	doc, errors := Parse("login.ft", content)
 	pf := Transform(doc, "login.ft", content, errors)
 
 	require.Len(t, pf.Errors, 1)
-	assert.Equal(t, "Scenario Outline is not supported", pf.Errors[0].Message)
+	assert.Equal(t, "Examples must belong to a Scenario Outline", pf.Errors[0].Message)
+}
+
+func TestTransform_ScenarioOutlineContentIncludesExamples(t *testing.T) {
+	content := []byte(`Feature: Login
+  @ft:1
+  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    @fast
+    Examples:
+      | role  |
+      | admin |
+
+  Scenario: User logs out
+    Given a user
+`)
+	doc, errors := Parse("login.ft", content)
+	pf := Transform(doc, "login.ft", content, errors)
+
+	require.Len(t, pf.Scenarios, 2)
+	assert.True(t, pf.Scenarios[0].Outline)
+	assert.Equal(t, "1", pf.Scenarios[0].FtTag)
+	expected := `  Scenario Outline: User logs in as <role>
+    Given a <role>
+
+    @fast
+    Examples:
+      | role  |
+      | admin |`
+	assert.Equal(t, expected, pf.Scenarios[0].Content)
+	assert.False(t, pf.Scenarios[1].Outline)
 }
 
 func TestTransform_NoFeatureLine(t *testing.T) {
@@ -112,3 +143,74 @@ func TestTransform_NoFeatureLine(t *testing.T) {
 
 	assert.Equal(t, "login", pf.Name)
 }
+
+func TestTransform_RuleScenarios(t *testing.T) {
+	content := []byte(`Feature: Refunds
+  Scenario: Refund page loads
+    Given the refund page
+
+  @billing
+  Rule: Refunds need a receipt
+    Background:
+      Given a purchase
+
+    @ft:7
+    Scenario: Refund with receipt
+      Given a receipt
+`)
+	doc, errors := Parse("refunds.ft", content)
+	pf := Transform(doc, "refunds.ft", content, errors)
+
+	require.Len(t, pf.Scenarios, 2)
+	assert.Equal(t, "", pf.Scenarios[0].Rule)
+	assert.Equal(t, "  Scenario: Refund page loads\n    Given the refund page", pf.Scenarios[0].Content)
+
+	assert.Equal(t, "Refunds need a receipt", pf.Scenarios[1].Rule)
+	assert.Equal(t, "7", pf.Scenarios[1].FtTag)
+	assert.Equal(t, "    Scenario: Refund with receipt\n      Given a receipt", pf.Scenarios[1].Content)
+}
+
+func TestTransform_SkipsScenariosInBrokenRegion(t *testing.T) {
+	content := []byte(`Feature: Login
+  Examples: Orphaned
+    | a |
+
+  Scenario: User logs in
+    Given a user
+
+  @ft:7
+  Scenario: User logs out
+    Given a user
+
+    Examples:
+      | b |
+
+  Scenario: User resets password
+    Given a user
+`)
+	doc, errors := Parse("login.ft", content)
+	pf := Transform(doc, "login.ft", content, errors)
+
+	require.Len(t, pf.Errors, 2)
+	require.Len(t, pf.Scenarios, 2)
+	assert.Equal(t, "User logs in", pf.Scenarios[0].Name)
+	assert.Equal(t, 6, pf.Scenarios[0].EndLine)
+	assert.Equal(t, "User resets password", pf.Scenarios[1].Name)
+	require.Len(t, pf.Skipped, 1)
+	assert.Equal(t, "7", pf.Skipped[0].FtTag)
+	assert.False(t, pf.Fatal())
+}
+
+func TestTransform_FatalErrorSkipsEveryScenario(t *testing.T) {
+	content := []byte(`# language: xx
+Feature: Login
+  Scenario: User logs in
+    Given a user
+`)
+	doc, errors := Parse("login.ft", content)
+	pf := Transform(doc, "login.ft", content, errors)
+
+	assert.True(t, pf.Fatal())
+	assert.Empty(t, pf.Scenarios)
+	assert.Len(t, pf.Skipped, 1)
+}
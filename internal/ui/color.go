@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/chriserin/ft/internal/parser"
 )
 
 var (
@@ -15,6 +16,7 @@ var (
 	errStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
 	modStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
 	delStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	renStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
 	plusStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
 	tildeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
 	minusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
@@ -41,10 +43,51 @@ func ModLine(w io.Writer, path string) {
 	fmt.Fprintln(w, modStyle.Render("mod")+"  "+path)
 }
 
+func RenLine(w io.Writer, from, to string) {
+	fmt.Fprintf(w, "%s  %s → %s\n", renStyle.Render("ren"), from, to)
+}
+
 func DelLine(w io.Writer, path string) {
 	fmt.Fprintln(w, delStyle.Render("del")+"  "+path)
 }
 
+func FmtLine(w io.Writer, path string) {
+	fmt.Fprintln(w, modStyle.Render("fmt")+"  "+path)
+}
+
+// ShowDiff prints a unified diff, coloring added and removed lines.
+func ShowDiff(w io.Writer, diff string) {
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Fprint(w, keywordStyle.Render(strings.TrimSuffix(line, "\n"))+"\n")
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprint(w, plusStyle.Render(strings.TrimSuffix(line, "\n"))+"\n")
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprint(w, minusStyle.Render(strings.TrimSuffix(line, "\n"))+"\n")
+		default:
+			fmt.Fprint(w, line)
+		}
+	}
+}
+
+func LintIssueLine(w io.Writer, path string, line int, rule, message string) {
+	fmt.Fprintf(w, "%s %s %s\n", fileStyle.Render(fmt.Sprintf("%s:%d:", path, line)), errStyle.Render(rule), message)
+}
+
+func DiagnosticLine(w io.Writer, path string, line, column int, severity, message string) {
+	fmt.Fprintf(w, "%s %s %s\n", fileStyle.Render(fmt.Sprintf("%s:%d:%d:", path, line, column)), errStyle.Render(severity), message)
+}
+
+func LintRuleLine(w io.Writer, name, description string, enabled bool) {
+	state := newStyle.Render("on ")
+	if !enabled {
+		state = delStyle.Render("off")
+	}
+	fmt.Fprintf(w, "%s  %-24s %s\n", state, name, description)
+}
+
 func ScenarioLine(w io.Writer, id int64, name string) {
 	fmt.Fprintf(w, "       %s %s %s\n", plusStyle.Render("+"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name)
 }
@@ -53,15 +96,32 @@ func ModifiedScenarioLine(w io.Writer, id int64, name string) {
 	fmt.Fprintf(w, "       %s %s %s\n", tildeStyle.Render("~"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name)
 }
 
+// MatchedScenarioLine reports a scenario without a tag that kept the id of
+// the stored scenario it resembles.
+func MatchedScenarioLine(w io.Writer, id int64, name, prevName string, score float64) {
+	fmt.Fprintf(w, "       %s %s %s %s\n", tildeStyle.Render("~"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name, fileStyle.Render(fmt.Sprintf("(matched %q, %.0f%% similar)", prevName, score*100)))
+}
+
+func MovedScenarioLine(w io.Writer, id int64, name, from string) {
+	fmt.Fprintf(w, "       %s %s %s %s\n", renStyle.Render(">"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name, fileStyle.Render("(from "+from+")"))
+}
+
 func RemovedScenarioLine(w io.Writer, id int64, name string) {
 	fmt.Fprintf(w, "       %s %s %s\n", minusStyle.Render("-"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name)
 }
 
-func ListRow(w io.Writer, id int64, fileName, scenarioName, status string, idWidth, fileWidth, nameWidth int) {
+// ListRow prints one row of `ft list`. The rule column is only printed when
+// ruleWidth is non-zero, i.e. when at least one listed scenario has a rule.
+func ListRow(w io.Writer, id int64, fileName, rule, scenarioName, status string, idWidth, fileWidth, ruleWidth, nameWidth int) {
 	tag := fmt.Sprintf("@ft:%d", id)
-	fmt.Fprintf(w, "%s  %-*s  %-*s  %s\n",
+	ruleCol := ""
+	if ruleWidth > 0 {
+		ruleCol = fmt.Sprintf("%-*s  ", ruleWidth, rule)
+	}
+	fmt.Fprintf(w, "%s  %-*s  %s%-*s  %s\n",
 		idStyle.Render(fmt.Sprintf("%-*s", idWidth, tag)),
 		fileWidth, fileName,
+		ruleCol,
 		nameWidth, scenarioName,
 		trkStyle.Render(status),
 	)
@@ -75,14 +135,11 @@ func ShowStatus(w io.Writer, status string) {
 	fmt.Fprintf(w, "Status: %s\n", trkStyle.Render(status))
 }
 
-// sectionKeywords are Gherkin keywords that start a section.
-var sectionKeywords = []string{"Background:", "Scenario:"}
-
-// stepKeywords are Gherkin step keywords.
-var stepKeywords = []string{"Given ", "When ", "Then ", "And ", "But "}
-
-// ShowGherkin prints Gherkin content with syntax coloring.
-func ShowGherkin(w io.Writer, content string) {
+// ShowGherkin prints Gherkin content with syntax coloring, recognizing the
+// keywords of the given language.
+func ShowGherkin(w io.Writer, lang *parser.Language, content string) {
+	sectionKeywords := lang.SectionKeywords()
+	stepKeywords := lang.StepKeywords()
 	lines := strings.Split(content, "\n")
 	inDocString := false
 	for _, line := range lines {
@@ -114,10 +171,9 @@ func ShowGherkin(w io.Writer, content string) {
 		}
 		// Check step keywords
 		for _, kw := range stepKeywords {
-			if strings.HasPrefix(trimmed, kw) {
+			if isStepKeyword(trimmed, kw) {
 				idx := strings.Index(line, kw)
-				kwTrimmed := strings.TrimRight(kw, " ")
-				fmt.Fprintln(w, line[:idx]+stepStyle.Render(kwTrimmed)+line[idx+len(kwTrimmed):])
+				fmt.Fprintln(w, line[:idx]+stepStyle.Render(kw)+line[idx+len(kw):])
 				rendered = true
 				break
 			}
@@ -129,6 +185,16 @@ func ShowGherkin(w io.Writer, content string) {
 	}
 }
 
+// isStepKeyword reports whether trimmed starts with the step keyword kw as a
+// whole word. Elided keywords such as French "Lorsqu'" need no space after.
+func isStepKeyword(trimmed, kw string) bool {
+	rest, ok := strings.CutPrefix(trimmed, kw)
+	if !ok {
+		return false
+	}
+	return strings.HasSuffix(kw, "'") || strings.HasPrefix(rest, " ")
+}
+
 type HistoryEntry struct {
 	Status    string
 	ChangedAt time.Time
@@ -187,3 +253,76 @@ func SummaryLine(w io.Writer, fileCount, scenarioCount int) {
 		fmt.Fprintf(w, "synced %d files\n", fileCount)
 	}
 }
+
+// DryRunSummaryLine is the summary of a sync that wrote nothing.
+func DryRunSummaryLine(w io.Writer, fileCount, scenarioCount int) {
+	if scenarioCount > 0 {
+		fmt.Fprintf(w, "would sync %d files, %d scenarios (dry run, nothing written)\n", fileCount, scenarioCount)
+	} else {
+		fmt.Fprintf(w, "would sync %d files (dry run, nothing written)\n", fileCount)
+	}
+}
+
+func PlanHeader(w io.Writer, title string) {
+	fmt.Fprintln(w, keywordStyle.Render(title+":"))
+}
+
+func PlannedTagLine(w io.Writer, path string, line int, id int64) {
+	fmt.Fprintf(w, "  %s %s\n", fileStyle.Render(fmt.Sprintf("%s:%d", path, line)), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)))
+}
+
+func PlannedStatusLine(w io.Writer, id int64, status string) {
+	fmt.Fprintf(w, "  %s %s\n", ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), status)
+}
+
+func PlannedLinkLine(w io.Writer, added bool, id int64, path string, line int) {
+	marker := plusStyle.Render("+")
+	if !added {
+		marker = minusStyle.Render("-")
+	}
+	fmt.Fprintf(w, "  %s %s %s:%d\n", marker, ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), path, line)
+}
+
+func PlannedWriteLine(w io.Writer, path string) {
+	fmt.Fprintln(w, "  "+path)
+}
+
+func RstLine(w io.Writer, path string) {
+	fmt.Fprintln(w, newStyle.Render("rst")+"  "+path)
+}
+
+// RehydratedScenarioLine reports a scenario written back into its file
+// because tests still link to it.
+func RehydratedScenarioLine(w io.Writer, id int64, name string) {
+	fmt.Fprintf(w, "       %s %s %s %s\n", errStyle.Render("!"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name, fileStyle.Render("(restored: has test links)"))
+}
+
+// RetaggedScenarioLine reports a scenario copied with another scenario's
+// @ft tag that was given a new id.
+func RetaggedScenarioLine(w io.Writer, id int64, name string, was int64) {
+	fmt.Fprintf(w, "       %s %s %s %s\n", plusStyle.Render("+"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name, fileStyle.Render(fmt.Sprintf("(was duplicate @ft:%d)", was)))
+}
+
+// OrphanedLinkLine reports a test tagged with a removed scenario.
+func OrphanedLinkLine(w io.Writer, path string, line int, id int64, name string) {
+	fmt.Fprintf(w, "  %s:%d → %s %s %s\n", path, line, ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name, minusStyle.Render("(removed)"))
+}
+
+// ConflictLine reports a removed scenario that tests still link to.
+func ConflictLine(w io.Writer, id int64, name string, tests []string) {
+	fmt.Fprintf(w, "%s  %s %s — still linked from %s\n", errStyle.Render("cfl"), ftTagStyle.Render(fmt.Sprintf("@ft:%d", id)), name, strings.Join(tests, ", "))
+}
+
+func ConflictHint(w io.Writer) {
+	fmt.Fprintln(w, trkStyle.Render("     restored to their files; remove the tests first, or run `ft sync --force` to remove them anyway"))
+}
+
+func DuplicateHint(w io.Writer) {
+	fmt.Fprintln(w, trkStyle.Render("     duplicate @ft tags are left unsynced; run `ft sync --fix-duplicates` to give the copies new ids"))
+}
+
+// WatchLine reports what ft watch is doing, between the sync output of its
+// passes.
+func WatchLine(w io.Writer, at time.Time, message string) {
+	fmt.Fprintf(w, "%s  %s %s\n", trkStyle.Render("wch"), fileStyle.Render(at.Format("15:04:05")), message)
+}